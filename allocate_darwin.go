@@ -0,0 +1,30 @@
+//go:build darwin
+
+package ppath
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// allocate reserves the space between the physical end of f and size,
+// preferring one contiguous extent.
+func allocate(f *os.File, current, size int64) error {
+	if size <= current {
+		return nil
+	}
+	store := unix.Fstore_t{
+		Flags:   unix.F_ALLOCATEALL | unix.F_ALLOCATECONTIG,
+		Posmode: unix.F_PEOFPOSMODE,
+		Length:  size - current,
+	}
+	err := unix.FcntlFstore(f.Fd(), unix.F_PREALLOCATE, &store)
+	if errors.Is(err, unix.ENOSPC) {
+		// No contiguous extent is large enough; accept a fragmented one.
+		store.Flags = unix.F_ALLOCATEALL
+		err = unix.FcntlFstore(f.Fd(), unix.F_PREALLOCATE, &store)
+	}
+	return err
+}
@@ -0,0 +1,18 @@
+//go:build linux
+
+package ppath
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// allocate reserves the first size bytes of f, filling any holes below its
+// current size as well.
+func allocate(f *os.File, _ int64, size int64) error {
+	if size == 0 {
+		return nil
+	}
+	return ignoringEINTR(func() error { return unix.Fallocate(int(f.Fd()), 0, 0, size) })
+}
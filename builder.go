@@ -0,0 +1,45 @@
+package ppath
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Builder accumulates path segments so that a path can be extended and
+// shortened again cheaply, for example to track the current path during a
+// recursive descent. Segments are joined with the OS separator without being
+// cleaned, and the Path is only materialized when Path is called. The zero
+// value is an empty builder; push the root as the first segment.
+type Builder struct {
+	buf   []byte
+	marks []int
+}
+
+// Push appends seg as a new segment.
+func (b *Builder) Push(seg string) {
+	b.marks = append(b.marks, len(b.buf))
+	if len(b.buf) > 0 && !os.IsPathSeparator(b.buf[len(b.buf)-1]) {
+		b.buf = append(b.buf, filepath.Separator)
+	}
+	b.buf = append(b.buf, seg...)
+}
+
+// Pop removes the segment added by the latest Push that has not been popped
+// yet. It does nothing if there is none.
+func (b *Builder) Pop() {
+	if len(b.marks) == 0 {
+		return
+	}
+	b.buf = b.buf[:b.marks[len(b.marks)-1]]
+	b.marks = b.marks[:len(b.marks)-1]
+}
+
+// Len returns the number of segments.
+func (b *Builder) Len() int {
+	return len(b.marks)
+}
+
+// Path returns the segments joined so far.
+func (b *Builder) Path() Path {
+	return Path(b.buf)
+}
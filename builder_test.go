@@ -0,0 +1,93 @@
+package ppath
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	var b Builder
+	if b.Path() != "" || b.Len() != 0 {
+		t.Errorf("expected empty builder, got %q with %d segments", b.Path(), b.Len())
+	}
+
+	root := string(filepath.Separator) + "root"
+	b.Push(root)
+	b.Push("a")
+	b.Push("b")
+	if expected := New(root, "a", "b"); b.Path() != expected {
+		t.Errorf("expected %s, got %s", expected, b.Path())
+	}
+	if b.Len() != 3 {
+		t.Errorf("expected 3 segments, got %d", b.Len())
+	}
+
+	b.Pop()
+	b.Push("c")
+	if expected := New(root, "a", "c"); b.Path() != expected {
+		t.Errorf("expected %s, got %s", expected, b.Path())
+	}
+
+	b.Pop()
+	b.Pop()
+	b.Pop()
+	b.Pop()
+	if b.Path() != "" || b.Len() != 0 {
+		t.Errorf("expected empty builder, got %q with %d segments", b.Path(), b.Len())
+	}
+
+	var fromRoot Builder
+	fromRoot.Push(string(filepath.Separator))
+	fromRoot.Push("etc")
+	if expected := New(string(filepath.Separator), "etc"); fromRoot.Path() != expected {
+		t.Errorf("expected %s, got %s", expected, fromRoot.Path())
+	}
+}
+
+// benchmarkSegments simulates a depth-first descent, visiting each node of a
+// tree with the given fan-out and depth.
+func benchmarkSegments(depth, fanout int, visit func(i int), leave func()) {
+	var descend func(level int)
+	descend = func(level int) {
+		if level == depth {
+			return
+		}
+		for i := range fanout {
+			visit(i)
+			descend(level + 1)
+			leave()
+		}
+	}
+	descend(0)
+}
+
+var benchmarkNames = func() []string {
+	s := make([]string, 8)
+	for i := range s {
+		s[i] = "segment" + strconv.Itoa(i)
+	}
+	return s
+}()
+
+func BenchmarkBuilder(b *testing.B) {
+	b.ReportAllocs()
+	for range b.N {
+		var pb Builder
+		pb.Push("/root")
+		benchmarkSegments(6, 4, func(i int) {
+			pb.Push(benchmarkNames[i])
+			_ = pb.Path()
+		}, pb.Pop)
+	}
+}
+
+func BenchmarkJoin(b *testing.B) {
+	b.ReportAllocs()
+	for range b.N {
+		stack := []Path{"/root"}
+		benchmarkSegments(6, 4, func(i int) {
+			stack = append(stack, stack[len(stack)-1].Join(benchmarkNames[i]))
+		}, func() { stack = stack[:len(stack)-1] })
+	}
+}
@@ -0,0 +1,153 @@
+package ppath
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+
+	"github.com/maa3x/errz"
+)
+
+// CachedFile serves reads of a file from a memory mapping. Each read stats
+// the file and remaps it when its identity, size or modification time has
+// changed, so reads never see stale content for long but cost one stat
+// instead of an open, read and close. It is meant for small, read-mostly files
+// that are read far more often than they change. It is safe for concurrent
+// use.
+//
+// Writers should replace the file atomically, by writing a temporary file and
+// renaming it over the path, rather than truncating and rewriting it in place:
+// on Unix, reading a mapped range of a file that has since been shortened
+// raises SIGBUS. On Windows the contents are copied into memory instead of
+// mapped.
+type CachedFile struct {
+	path Path
+
+	mu     sync.RWMutex
+	data   []byte
+	info   fs.FileInfo
+	closed bool
+}
+
+// Cached maps the file into memory and returns a CachedFile serving reads
+// from the mapping. Call Close to release the mapping.
+func (p Path) Cached() (*CachedFile, error) {
+	c := &CachedFile{path: p}
+	info, err := os.Stat(string(p))
+	if err != nil {
+		return nil, err
+	}
+	if err := c.remap(info); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ReadFile returns a copy of the current contents of the file.
+func (c *CachedFile) ReadFile() ([]byte, error) {
+	if err := c.refresh(); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		return nil, os.ErrClosed
+	}
+	return append([]byte{}, c.data...), nil
+}
+
+// ReadAt implements io.ReaderAt on the current contents of the file.
+func (c *CachedFile) ReadAt(b []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errz.E("negative offset").With("offset", off)
+	}
+	if err := c.refresh(); err != nil {
+		return 0, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		return 0, os.ErrClosed
+	}
+	if off >= int64(len(c.data)) {
+		return 0, io.EOF
+	}
+	n := copy(b, c.data[off:])
+	if n < len(b) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Close releases the mapping. Reads after Close return os.ErrClosed.
+func (c *CachedFile) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	err := unmapFile(c.data)
+	c.data = nil
+	return err
+}
+
+// refresh remaps the file if it changed since it was last mapped.
+func (c *CachedFile) refresh() error {
+	info, err := os.Stat(string(c.path))
+	if err != nil {
+		return err
+	}
+
+	c.mu.RLock()
+	stale := !c.closed && c.changed(info)
+	c.mu.RUnlock()
+	if !stale {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed || !c.changed(info) {
+		return nil
+	}
+	return c.remap(info)
+}
+
+func (c *CachedFile) changed(info fs.FileInfo) bool {
+	return !os.SameFile(c.info, info) || c.info.Size() != info.Size() || !c.info.ModTime().Equal(info.ModTime())
+}
+
+// remap replaces the mapping with a fresh one. The caller must hold the write
+// lock.
+func (c *CachedFile) remap(info fs.FileInfo) error {
+	if !info.Mode().IsRegular() {
+		return errz.E("not a regular file").With("path", c.path)
+	}
+
+	// Mapping needs an OS file descriptor, so this bypasses SetFileSystem.
+	f, err := os.Open(string(c.path))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Stat the opened file so the mapping and its info always agree, even if
+	// the path was replaced in between.
+	if info, err = f.Stat(); err != nil {
+		return errz.E(err, "stat file").With("path", c.path)
+	}
+	data, err := mapFile(f, info.Size())
+	if err != nil {
+		return errz.E(err, "map file").With("path", c.path)
+	}
+	if err := unmapFile(c.data); err != nil {
+		unmapFile(data)
+		return errz.E(err, "unmap file").With("path", c.path)
+	}
+	c.data, c.info = data, info
+	return nil
+}
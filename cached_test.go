@@ -0,0 +1,114 @@
+package ppath
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCached(t *testing.T) {
+	tempDir := New(t.TempDir())
+	p := tempDir.Join("static.txt")
+	if err := p.WriteFile([]byte("version 1")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	c, err := p.Cached()
+	if err != nil {
+		t.Fatalf("Cached: %v", err)
+	}
+	defer c.Close()
+
+	if data, err := c.ReadFile(); err != nil || string(data) != "version 1" {
+		t.Errorf("expected version 1, got %q, error: %v", data, err)
+	}
+
+	buf := make([]byte, 4)
+	if n, err := c.ReadAt(buf, 8); n != 1 || !errors.Is(err, io.EOF) || string(buf[:n]) != "1" {
+		t.Errorf("expected 1 byte and EOF, got %d %q, error: %v", n, buf[:n], err)
+	}
+
+	// Replace the file atomically with different content of another size.
+	tmp := tempDir.Join("static.tmp")
+	if err := tmp.WriteFile([]byte("version 22")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.Rename(string(tmp), string(p)); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if data, err := c.ReadFile(); err != nil || string(data) != "version 22" {
+		t.Errorf("expected version 22, got %q, error: %v", data, err)
+	}
+
+	// Same size, newer modification time.
+	if err := tmp.WriteFile([]byte("version 33")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(string(tmp), later, later); err != nil {
+		t.Fatalf("Chtimes: %v", err)
+	}
+	if err := os.Rename(string(tmp), string(p)); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if data, err := c.ReadFile(); err != nil || string(data) != "version 33" {
+		t.Errorf("expected version 33, got %q, error: %v", data, err)
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				if data, err := c.ReadFile(); err != nil || string(data) != "version 33" {
+					t.Errorf("expected version 33, got %q, error: %v", data, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, err := c.ReadFile(); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("expected os.ErrClosed, got %v", err)
+	}
+}
+
+func TestCachedEmptyAndMissing(t *testing.T) {
+	tempDir := New(t.TempDir())
+	empty := tempDir.Join("empty")
+	if err := empty.WriteFile(nil); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	c, err := empty.Cached()
+	if err != nil {
+		t.Fatalf("Cached: %v", err)
+	}
+	if data, err := c.ReadFile(); err != nil || len(data) != 0 {
+		t.Errorf("expected empty content, got %q, error: %v", data, err)
+	}
+
+	if err := empty.Remove(); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if _, err := c.ReadFile(); err == nil {
+		t.Errorf("expected error after removal, got nil")
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+
+	if _, err := tempDir.Join("missing").Cached(); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+	if _, err := tempDir.Cached(); err == nil {
+		t.Errorf("expected error for a directory, got nil")
+	}
+}
@@ -0,0 +1,117 @@
+package ppath
+
+import (
+	"cmp"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Compare orders p and other lexically and returns -1, 0 or +1. Paths are
+// compared in slash-separated form segment by segment, so a directory sorts
+// directly before its contents ("a", "a/b", "a-b") and the order is the same
+// on every platform. Paths are not cleaned first.
+func (p Path) Compare(other Path) int {
+	a, b := filepath.ToSlash(string(p)), filepath.ToSlash(string(other))
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := cmp.Compare(sortKey(a[i]), sortKey(b[i])); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(a), len(b))
+}
+
+// Less reports whether p sorts before other according to Compare.
+func (p Path) Less(other Path) bool {
+	return p.Compare(other) < 0
+}
+
+// CompareNatural is like Compare but orders runs of digits by their numeric
+// value, so "file2" sorts before "file10". Numbers that only differ in leading
+// zeros are ordered by their length, so "file1" sorts before "file01".
+func (p Path) CompareNatural(other Path) int {
+	return compareNatural(string(p), string(other), false)
+}
+
+// NaturalLess reports whether a sorts before b according to CompareNatural.
+func NaturalLess(a, b Path) bool {
+	return a.CompareNatural(b) < 0
+}
+
+// NaturalLessFold is like NaturalLess but ignores case. Paths that only differ
+// in case are ordered as NaturalLess orders them, so sorting stays
+// deterministic.
+func NaturalLessFold(a, b Path) bool {
+	return compareNaturalFold(a, b) < 0
+}
+
+func compareNaturalFold(a, b Path) int {
+	if c := compareNatural(string(a), string(b), true); c != 0 {
+		return c
+	}
+	return a.CompareNatural(b)
+}
+
+func compareNatural(a, b string, fold bool) int {
+	a, b = filepath.ToSlash(a), filepath.ToSlash(b)
+	for len(a) > 0 && len(b) > 0 {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			na, restA := digitPrefix(a)
+			nb, restB := digitPrefix(b)
+			ta, tb := strings.TrimLeft(na, "0"), strings.TrimLeft(nb, "0")
+			if c := cmp.Compare(len(ta), len(tb)); c != 0 {
+				return c
+			}
+			if c := strings.Compare(ta, tb); c != 0 {
+				return c
+			}
+			if c := cmp.Compare(len(na), len(nb)); c != 0 {
+				return c
+			}
+			a, b = restA, restB
+			continue
+		}
+
+		if !fold {
+			if c := cmp.Compare(sortKey(a[0]), sortKey(b[0])); c != 0 {
+				return c
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+
+		ra, sizeA := utf8.DecodeRuneInString(a)
+		rb, sizeB := utf8.DecodeRuneInString(b)
+		if c := cmp.Compare(runeSortKey(unicode.ToLower(ra)), runeSortKey(unicode.ToLower(rb))); c != 0 {
+			return c
+		}
+		a, b = a[sizeA:], b[sizeB:]
+	}
+	return cmp.Compare(len(a), len(b))
+}
+
+// sortKey makes the separator sort before every other byte, which orders
+// paths segment by segment.
+func sortKey(c byte) int {
+	return runeSortKey(rune(c))
+}
+
+func runeSortKey(r rune) int {
+	if r == '/' {
+		return -1
+	}
+	return int(r)
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func digitPrefix(s string) (digits, rest string) {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return s[:i], s[i:]
+}
@@ -0,0 +1,68 @@
+package ppath
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b Path
+		want int
+	}{
+		{"a", "a", 0},
+		{"a", "b", -1},
+		{"b", "a", 1},
+		{"a", "a/b", -1},
+		{"a/b", "a-b", -1},
+		{"a/z", "a.b", -1},
+		{"file10", "file2", -1},
+	}
+
+	for _, tt := range tests {
+		if got := tt.a.Compare(tt.b); got != tt.want {
+			t.Errorf("Compare(%s, %s): expected %d, got %d", tt.a, tt.b, tt.want, got)
+		}
+		if got := tt.a.Less(tt.b); got != (tt.want < 0) {
+			t.Errorf("Less(%s, %s): expected %v, got %v", tt.a, tt.b, tt.want < 0, got)
+		}
+	}
+
+	paths := []Path{"a-b", "a/c", "a", "a/b/c", "B", "a/b"}
+	slices.SortFunc(paths, Path.Compare)
+	expected := []Path{"B", "a", "a/b", "a/b/c", "a/c", "a-b"}
+	if !slices.Equal(paths, expected) {
+		t.Errorf("expected %v, got %v", expected, paths)
+	}
+}
+
+func TestCompareNatural(t *testing.T) {
+	tests := []struct {
+		a, b Path
+		want int
+	}{
+		{"file2", "file10", -1},
+		{"file10", "file2", 1},
+		{"file10", "file10", 0},
+		{"file1", "file01", -1},
+		{"file007", "file8", -1},
+		{"v1.10/a", "v1.9/a", 1},
+		{"dir2/x", "dir10", -1},
+		{"a", "a/1", -1},
+		{"a/1", "a-1", -1},
+		{"x1y", "x1", 1},
+	}
+
+	for _, tt := range tests {
+		if got := tt.a.CompareNatural(tt.b); got != tt.want {
+			t.Errorf("CompareNatural(%s, %s): expected %d, got %d", tt.a, tt.b, tt.want, got)
+		}
+	}
+
+	paths := []Path{"img12.png", "img10.png", "img2.png", "img1.png"}
+	slices.SortFunc(paths, Path.CompareNatural)
+	expected := []Path{"img1.png", "img2.png", "img10.png", "img12.png"}
+	if !slices.Equal(paths, expected) {
+		t.Errorf("expected %v, got %v", expected, paths)
+	}
+}
@@ -0,0 +1,262 @@
+package ppath
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/maa3x/errz"
+)
+
+// Copier copies files and directory trees with a fixed set of options. The
+// zero value recreates symlinks rather than following them, overwrites
+// existing files, and gives new files and directories default permissions and
+// the current time. A Copier can be reused for any number of copies.
+type Copier struct {
+	// PreservePerm copies the permission bits of files and directories.
+	PreservePerm bool
+	// PreserveTimes copies the modification time of files and directories.
+	PreserveTimes bool
+	// PreserveXattrs copies the extended attributes of files and directories.
+	// Attributes the destination cannot store make the copy fail, while a
+	// source without extended attribute support has nothing to copy.
+	PreserveXattrs bool
+	// FollowSymlinks copies what symlinks point to instead of recreating the
+	// links. A link back to a directory being copied makes the copy fail with
+	// an error matching ErrSymlinkCycle, before anything is created for it.
+	FollowSymlinks bool
+	// BufferSize is the size of the buffer used to copy file contents. Zero
+	// means 32 KiB. Larger buffers can speed up copying large files on fast
+	// storage. Buffers are pooled and reused across copies.
+	BufferSize int
+	// OnConflict decides what happens to files that already exist at the
+	// destination.
+	OnConflict ConflictPolicy
+	// Exclude lists patterns of entries to leave out, matched against the
+	// slash-separated path relative to the copied directory as in WalkMatch,
+	// or against the base name when copying a single file. An excluded
+	// directory is left out with everything below it.
+	Exclude []string
+	// Progress, if set, is called after each chunk of a file is written with
+	// the source file, the bytes written to it so far and its total size.
+	Progress func(src Path, written, size int64)
+}
+
+// Copy copies src to dst. A file is copied to dst, or into dst under its base
+// name if dst is a directory. A directory's contents are copied into dst,
+// which is created if needed, merging with what is already there.
+func (c *Copier) Copy(src, dst Path) error {
+	for _, pattern := range c.Exclude {
+		if err := validPattern(pattern); err != nil {
+			return errz.E(err, "invalid exclude pattern").With("pattern", pattern)
+		}
+	}
+
+	fi, err := c.stat(src)
+	if err != nil {
+		return errz.E(err, "stat source").With("path", src)
+	}
+	if fi.IsDir() {
+		return c.copyDir(src, dst, "", fi, make(map[FileID]string))
+	}
+
+	if c.excluded(src.Base().String()) {
+		return nil
+	}
+	if dst.IsDir() {
+		dst = dst.JoinPath(src.Base())
+	}
+	return c.copyEntry(src, dst, fi)
+}
+
+// copyDir copies the directory src into dst. When following symlinks,
+// ancestors holds the FileID of every directory being copied on the way down
+// to src, as in WalkFollow, so a link back to one of them fails with
+// ErrSymlinkCycle before anything is created for it.
+func (c *Copier) copyDir(src, dst Path, rel string, fi fs.FileInfo, ancestors map[FileID]string) error {
+	if c.FollowSymlinks {
+		id, ok := infoID(fi)
+		if !ok {
+			var err error
+			if id, err = fileID(string(src)); err != nil {
+				return errz.E(err, "identify directory").With("path", src)
+			}
+		}
+		if ancestor, ok := ancestors[id]; ok {
+			target, _ := os.Readlink(string(src))
+			return errz.E(ErrSymlinkCycle).With("link", src).With("target", target).With("ancestor", ancestor)
+		}
+		ancestors[id] = string(src)
+		defer delete(ancestors, id)
+	}
+
+	if err := dst.MkdirIfNotExist(); err != nil {
+		return errz.E(err, "create directory").With("path", dst)
+	}
+
+	entries, err := os.ReadDir(string(src))
+	if err != nil {
+		return errz.E(err, "read directory").With("path", src)
+	}
+	for _, e := range entries {
+		childRel := path.Join(rel, e.Name())
+		if c.excluded(childRel) {
+			continue
+		}
+
+		child := src.Entry(e)
+		cfi, err := c.stat(child)
+		if err != nil {
+			return errz.E(err, "stat").With("path", child)
+		}
+		if cfi.IsDir() {
+			err = c.copyDir(child, dst.Join(e.Name()), childRel, cfi, ancestors)
+		} else {
+			err = c.copyEntry(child, dst.Join(e.Name()), cfi)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// Applied after the contents, so a read-only directory can be filled.
+	return c.applyMetadata(src, dst, fi)
+}
+
+func (c *Copier) copyEntry(src, dst Path, fi fs.FileInfo) error {
+	isLink := fi.Mode()&fs.ModeSymlink != 0
+	if !isLink && !fi.Mode().IsRegular() {
+		return errz.E("unsupported file type").With("path", src).With("type", fi.Mode().Type())
+	}
+
+	if dfi, err := dst.LStat(); err == nil {
+		if dfi.IsDir() {
+			return errz.E("destination is a directory").With("path", dst)
+		}
+		var ok bool
+		if dst, ok, err = resolveConflict(fi, dst, c.OnConflict); err != nil || !ok {
+			return err
+		}
+	}
+
+	if isLink {
+		target, err := os.Readlink(string(src))
+		if err != nil {
+			return errz.E(err, "read symlink").With("path", src)
+		}
+		if err := os.Symlink(target, string(dst)); err != nil {
+			return errz.E(err, "create symlink").With("path", dst)
+		}
+		return nil
+	}
+
+	if err := c.copyContent(src, dst, fi.Size()); err != nil {
+		return errz.E(err, "copy file").With("path", src)
+	}
+	return c.applyMetadata(src, dst, fi)
+}
+
+func (c *Copier) copyContent(src, dst Path, size int64) error {
+	in, err := src.OpenHandle()
+	if err != nil {
+		return errz.E(err, "open source file")
+	}
+	defer in.Close()
+
+	out, err := dst.OpenFileHandle(os.O_WRONLY|os.O_CREATE|os.O_TRUNC, defaultFilePerm())
+	if err != nil {
+		return errz.E(err, "open destination file")
+	}
+
+	bufSize := c.BufferSize
+	if bufSize <= 0 {
+		bufSize = 32 * 1024
+	}
+	buf := getCopyBuffer(bufSize)
+	defer putCopyBuffer(buf)
+
+	var w io.Writer = out
+	if c.Progress != nil {
+		w = &progressWriter{w: out, fn: func(n int64) { c.Progress(src, n, size) }}
+	}
+	// Hide ReaderFrom and WriterTo so the configured buffer is really used.
+	if _, err := io.CopyBuffer(struct{ io.Writer }{w}, struct{ io.Reader }{in}, *buf); err != nil {
+		out.Close()
+		return errz.E(err, "copy content")
+	}
+	return out.Close()
+}
+
+// copyBufferPools holds a *sync.Pool of copy buffers for each buffer size in
+// use, so copying many files does not allocate a buffer per file.
+var copyBufferPools sync.Map
+
+func getCopyBuffer(size int) *[]byte {
+	pool, ok := copyBufferPools.Load(size)
+	if !ok {
+		pool, _ = copyBufferPools.LoadOrStore(size, &sync.Pool{
+			New: func() any {
+				buf := make([]byte, size)
+				return &buf
+			},
+		})
+	}
+	return pool.(*sync.Pool).Get().(*[]byte)
+}
+
+func putCopyBuffer(buf *[]byte) {
+	if pool, ok := copyBufferPools.Load(len(*buf)); ok {
+		pool.(*sync.Pool).Put(buf)
+	}
+}
+
+func (c *Copier) applyMetadata(src, dst Path, fi fs.FileInfo) error {
+	// Copied first, as setting attributes may need write permission.
+	if c.PreserveXattrs {
+		if err := copyXattrs(src, dst); err != nil {
+			return err
+		}
+	}
+	if c.PreservePerm {
+		if err := os.Chmod(string(dst), fi.Mode().Perm()); err != nil {
+			return errz.E(err, "set permissions").With("path", dst)
+		}
+	}
+	if c.PreserveTimes {
+		if err := os.Chtimes(string(dst), fi.ModTime(), fi.ModTime()); err != nil {
+			return errz.E(err, "set times").With("path", dst)
+		}
+	}
+	return nil
+}
+
+func (c *Copier) stat(p Path) (fs.FileInfo, error) {
+	if c.FollowSymlinks {
+		return p.Stat()
+	}
+	return p.LStat()
+}
+
+func (c *Copier) excluded(rel string) bool {
+	for _, pattern := range c.Exclude {
+		if matchPath(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+type progressWriter struct {
+	w       io.Writer
+	written int64
+	fn      func(written int64)
+}
+
+func (pw *progressWriter) Write(b []byte) (int, error) {
+	n, err := pw.w.Write(b)
+	pw.written += int64(n)
+	pw.fn(pw.written)
+	return n, err
+}
@@ -0,0 +1,227 @@
+package ppath
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestCopier(t *testing.T) {
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	setup := func(t *testing.T) (Path, Path) {
+		tempDir := New(t.TempDir())
+		src, dst := tempDir.Join("src"), tempDir.Join("dst")
+		for name, content := range map[string]string{
+			"a.txt":         "a",
+			"run.sh":        "run",
+			"sub/b.txt":     "b",
+			"sub/c.tmp":     "c",
+			"cache/d.txt":   "d",
+			"sub/deep/e.go": "e",
+		} {
+			if err := src.Join(name).WriteFile([]byte(content)); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+		}
+		if err := os.Chmod(src.Join("run.sh").String(), 0o700); err != nil {
+			t.Fatalf("os.Chmod: %v", err)
+		}
+		if err := os.Chtimes(src.Join("a.txt").String(), mtime, mtime); err != nil {
+			t.Fatalf("os.Chtimes: %v", err)
+		}
+		if err := os.Symlink("a.txt", src.Join("link").String()); err != nil {
+			t.Fatalf("os.Symlink: %v", err)
+		}
+		return src, dst
+	}
+	check := func(t *testing.T, p Path, expected string) {
+		t.Helper()
+		content, err := p.ReadFile()
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		if string(content) != expected {
+			t.Errorf("expected %q in %s, got %q", expected, p, content)
+		}
+	}
+
+	t.Run("Default", func(t *testing.T) {
+		src, dst := setup(t)
+		var c Copier
+		if err := c.Copy(src, dst); err != nil {
+			t.Fatalf("Copy: %v", err)
+		}
+		check(t, dst.Join("sub", "deep", "e.go"), "e")
+		if target, err := os.Readlink(dst.Join("link").String()); err != nil || target != "a.txt" {
+			t.Errorf("expected link to a.txt, got %q, error: %v", target, err)
+		}
+		if runtime.GOOS != "windows" {
+			if fi, _ := dst.Join("run.sh").Stat(); fi.Mode().Perm() != 0o644 {
+				t.Errorf("expected default mode 644, got %o", fi.Mode().Perm())
+			}
+		}
+	})
+
+	t.Run("Preserve", func(t *testing.T) {
+		src, dst := setup(t)
+		c := Copier{PreservePerm: true, PreserveTimes: true, FollowSymlinks: true}
+		if err := c.Copy(src, dst); err != nil {
+			t.Fatalf("Copy: %v", err)
+		}
+		if runtime.GOOS != "windows" {
+			if fi, _ := dst.Join("run.sh").Stat(); fi.Mode().Perm() != 0o700 {
+				t.Errorf("expected mode 700, got %o", fi.Mode().Perm())
+			}
+		}
+		if _, modified, _ := dst.Join("a.txt").Times(); !modified.Equal(mtime) {
+			t.Errorf("expected mtime %v, got %v", mtime, modified)
+		}
+		if dst.Join("link").IsSymlink() {
+			t.Errorf("expected link to be followed")
+		}
+		check(t, dst.Join("link"), "a")
+	})
+
+	t.Run("PreserveXattrs", func(t *testing.T) {
+		src, dst := setup(t)
+		if err := src.Join("sub", "b.txt").SetXattr(xattrName, []byte("b")); err != nil {
+			t.Skipf("SetXattr: %v", err)
+		}
+		if err := src.Join("sub").SetXattr(xattrName, []byte("dir")); err != nil {
+			t.Fatalf("SetXattr: %v", err)
+		}
+		if err := os.Chmod(src.Join("sub", "b.txt").String(), 0o444); err != nil {
+			t.Fatalf("os.Chmod: %v", err)
+		}
+
+		c := Copier{PreservePerm: true, PreserveXattrs: true}
+		if err := c.Copy(src, dst); err != nil {
+			t.Fatalf("Copy: %v", err)
+		}
+		for name, expected := range map[string]string{"sub/b.txt": "b", "sub": "dir"} {
+			if value, err := dst.Join(name).GetXattr(xattrName); err != nil || string(value) != expected {
+				t.Errorf("%s: expected %q, got %q, error: %v", name, expected, value, err)
+			}
+		}
+		if _, err := dst.Join("a.txt").GetXattr(xattrName); !errors.Is(err, ErrNoXattr) {
+			t.Errorf("expected ErrNoXattr, got %v", err)
+		}
+	})
+
+	t.Run("Exclude", func(t *testing.T) {
+		src, dst := setup(t)
+		c := Copier{Exclude: []string{"**/*.tmp", "cache"}}
+		if err := c.Copy(src, dst); err != nil {
+			t.Fatalf("Copy: %v", err)
+		}
+		if dst.Join("sub", "c.tmp").Exists() || dst.Join("cache").Exists() {
+			t.Errorf("expected excluded entries to be left out")
+		}
+		check(t, dst.Join("sub", "b.txt"), "b")
+
+		if err := (&Copier{Exclude: []string{"["}}).Copy(src, dst); err == nil {
+			t.Errorf("expected error for bad pattern, got nil")
+		}
+	})
+
+	t.Run("OnConflict", func(t *testing.T) {
+		src, dst := setup(t)
+		if err := dst.Join("a.txt").WriteFile([]byte("existing")); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		c := Copier{OnConflict: ConflictSkip}
+		if err := c.Copy(src, dst); err != nil {
+			t.Fatalf("Copy: %v", err)
+		}
+		check(t, dst.Join("a.txt"), "existing")
+		check(t, dst.Join("sub", "b.txt"), "b")
+	})
+
+	t.Run("Progress", func(t *testing.T) {
+		src, dst := setup(t)
+		if err := src.Join("big.bin").WriteFile(make([]byte, 10000)); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		var calls int
+		var last, size int64
+		c := Copier{BufferSize: 4096, Progress: func(p Path, written, total int64) {
+			if p.Base() == "big.bin" {
+				calls++
+				last, size = written, total
+			}
+		}}
+		if err := c.Copy(src.Join("big.bin"), dst); err != nil {
+			t.Fatalf("Copy: %v", err)
+		}
+		if calls != 3 || last != 10000 || size != 10000 {
+			t.Errorf("expected 3 calls ending at 10000/10000, got %d calls, %d/%d", calls, last, size)
+		}
+		if !dst.IsRegular() {
+			t.Errorf("expected file to be copied to %s", dst)
+		}
+	})
+
+	t.Run("FileIntoDirectory", func(t *testing.T) {
+		src, dst := setup(t)
+		if err := dst.MkdirIfNotExist(); err != nil {
+			t.Fatalf("MkdirIfNotExist: %v", err)
+		}
+		var c Copier
+		if err := c.Copy(src.Join("a.txt"), dst); err != nil {
+			t.Fatalf("Copy: %v", err)
+		}
+		check(t, dst.Join("a.txt"), "a")
+	})
+
+	t.Run("SymlinkCycle", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("directory symlinks need privileges")
+		}
+		src, dst := setup(t)
+		if err := os.Symlink(".", src.Join("sub", "loop").String()); err != nil {
+			t.Fatalf("os.Symlink: %v", err)
+		}
+		c := Copier{FollowSymlinks: true}
+		if err := c.Copy(src, dst); !errors.Is(err, ErrSymlinkCycle) {
+			t.Errorf("expected ErrSymlinkCycle, got %v", err)
+		}
+		if dst.Join("sub", "loop").LExists() {
+			t.Errorf("expected nothing to be created for the looping link")
+		}
+	})
+
+	t.Run("UnsupportedType", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("no /dev/null")
+		}
+		_, dst := setup(t)
+		var c Copier
+		if err := c.Copy("/dev/null", dst.Join("null")); err == nil {
+			t.Errorf("expected error for a device, got nil")
+		}
+	})
+}
+
+func BenchmarkCopierBufferSize(b *testing.B) {
+	tempDir := New(b.TempDir())
+	src := tempDir.Join("src.bin")
+	if err := src.WriteFile(make([]byte, 8<<20)); err != nil {
+		b.Fatalf("WriteFile: %v", err)
+	}
+	dst := tempDir.Join("dst.bin")
+
+	for _, size := range []int{32 << 10, 256 << 10, 1 << 20} {
+		b.Run(HumanSize(int64(size)), func(b *testing.B) {
+			c := Copier{BufferSize: size}
+			b.SetBytes(8 << 20)
+			b.ReportAllocs()
+			for range b.N {
+				if err := c.Copy(src, dst); err != nil {
+					b.Fatalf("Copy: %v", err)
+				}
+			}
+		})
+	}
+}
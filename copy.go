@@ -0,0 +1,558 @@
+package ppath
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/maa3x/errz"
+)
+
+// CopyWith is like Copy but resolves existing destination files according to
+// onConflict. Directories are copied recursively, merging into an existing
+// destination directory and applying the policy to each file. Only regular
+// files and directories can be copied.
+func (p Path) CopyWith(dst Path, onConflict ConflictPolicy) error {
+	if !p.IsDir() {
+		if dst.IsDir() {
+			dst = dst.JoinPath(p.Base())
+		}
+		return p.copyFileWith(dst, onConflict)
+	}
+
+	return p.Walk(func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := Path(path).Rel(p)
+		if err != nil {
+			return err
+		}
+		target := dst.JoinPath(rel)
+
+		switch {
+		case d.IsDir():
+			return target.MkdirIfNotExist()
+		case d.Type().IsRegular():
+			return Path(path).copyFileWith(target, onConflict)
+		default:
+			return errz.E("unsupported file type").With("path", path)
+		}
+	})
+}
+
+// CopyContents copies the children of the directory p into dst, creating dst
+// if needed, so that p's entries end up directly inside dst rather than in a
+// nested directory. Existing files in dst are overwritten.
+func (p Path) CopyContents(dst Path) error {
+	return p.CopyContentsWith(dst, ConflictOverwrite)
+}
+
+// CopyContentsWith is like CopyContents but resolves existing destination
+// files according to onConflict. Permissions of copied files and directories
+// are preserved; directories get theirs once their contents are copied, and
+// dst itself keeps its own. Only regular files and directories can be copied.
+func (p Path) CopyContentsWith(dst Path, onConflict ConflictPolicy) error {
+	if !p.IsDir() {
+		return errz.E("source is not a directory").With("path", p)
+	}
+	if err := dst.MkdirIfNotExist(); err != nil {
+		return errz.E(err, "create destination directory").With("path", dst)
+	}
+
+	type dirMode struct {
+		path Path
+		perm fs.FileMode
+	}
+	var dirs []dirMode
+
+	err := p.Walk(func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == string(p) {
+			return nil
+		}
+		rel, err := Path(path).Rel(p)
+		if err != nil {
+			return err
+		}
+		target := dst.JoinPath(rel)
+
+		fi, err := d.Info()
+		if err != nil {
+			return errz.E(err, "stat").With("path", path)
+		}
+		switch {
+		case fi.IsDir():
+			if err := target.MkdirIfNotExist(); err != nil {
+				return err
+			}
+			dirs = append(dirs, dirMode{target, fi.Mode().Perm()})
+			return nil
+		case fi.Mode().IsRegular():
+			if target.Exists() {
+				if !target.IsRegular() {
+					return errz.E("destination is not a regular file").With("path", target)
+				}
+				var ok bool
+				if target, ok, err = resolveConflict(fi, target, onConflict); err != nil || !ok {
+					return err
+				}
+			}
+			if err := copyFileContent(Path(path), target, fi.Mode().Perm()); err != nil {
+				return errz.E(err, "copy file").With("path", path)
+			}
+			return os.Chmod(string(target), fi.Mode().Perm())
+		default:
+			return errz.E("unsupported file type").With("path", path)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, d := range slices.Backward(dirs) {
+		if err := os.Chmod(string(d.path), d.perm); err != nil {
+			return errz.E(err, "set permissions").With("path", d.path)
+		}
+	}
+	return nil
+}
+
+func (p Path) copyFileWith(dst Path, onConflict ConflictPolicy) error {
+	if dst.Exists() {
+		if !dst.IsRegular() {
+			return errz.E("destination is not a regular file").With("path", dst)
+		}
+
+		fi, err := p.Stat()
+		if err != nil {
+			return errz.E(err, "stat source file")
+		}
+		var ok bool
+		if dst, ok, err = resolveConflict(fi, dst, onConflict); err != nil || !ok {
+			return err
+		}
+	}
+	return p.copyFile(dst)
+}
+
+// CopyResume copies the regular file p to dst, resuming an earlier
+// interrupted copy when possible. If dst already holds a prefix of p, verified
+// by hashing the overlapping bytes of both files, only the remaining bytes are
+// appended. Otherwise dst is rewritten from scratch. If dst is a directory the
+// file is copied into it under its base name.
+//
+// Resuming is only safe for immutable or append-only sources. The prefix check
+// reads the already-copied range on both sides, and it cannot detect changes
+// made to the source while the copy is running.
+func (p Path) CopyResume(dst Path) error {
+	if dst.IsDir() {
+		dst = dst.JoinPath(p.Base())
+	}
+
+	src, err := p.OpenHandle()
+	if err != nil {
+		return errz.E(err, "open source file")
+	}
+	defer src.Close()
+
+	srcInfo, err := src.Stat()
+	if err != nil {
+		return errz.E(err, "stat source file")
+	}
+	done, err := dst.Size()
+	if err != nil || done == 0 || done > srcInfo.Size() {
+		return p.Copy(dst)
+	}
+
+	match, err := samePrefix(src, dst, done)
+	if err != nil {
+		return err
+	}
+	if !match {
+		return p.Copy(dst)
+	}
+	if done == srcInfo.Size() {
+		return nil
+	}
+
+	if _, err := src.Seek(done, io.SeekStart); err != nil {
+		return errz.E(err, "seek source file")
+	}
+	dest, err := dst.OpenFileHandle(os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return errz.E(err, "open destination file")
+	}
+	defer dest.Close()
+
+	if _, err := io.Copy(dest, src); err != nil {
+		return errz.E(err, "copy remaining bytes")
+	}
+	return nil
+}
+
+// sparseBlockSize is the granularity at which CopySparse detects holes. It
+// matches the common filesystem block size.
+const sparseBlockSize = 4096
+
+// CopySparse copies the regular file p to dst like Copy, but seeks over
+// blocks that are entirely zero instead of writing them, so holes in sparse
+// files such as disk images stay holes in the copy. The copy is byte-identical
+// either way; on filesystems without sparse file support the skipped ranges
+// are simply allocated as zeros. If dst is a directory the file is copied into
+// it under its base name.
+func (p Path) CopySparse(dst Path) error {
+	if dst.IsDir() {
+		dst = dst.JoinPath(p.Base())
+	}
+
+	src, err := p.OpenHandle()
+	if err != nil {
+		return errz.E(err, "open source file")
+	}
+	defer src.Close()
+
+	if err := dst.Dir().MkdirIfNotExist(); err != nil {
+		return errz.E(err, "create parent directory")
+	}
+	dest, err := dst.OpenFileHandle(os.O_WRONLY|os.O_CREATE|os.O_TRUNC, defaultFilePerm())
+	if err != nil {
+		return errz.E(err, "open destination file")
+	}
+	defer dest.Close()
+
+	buf := make([]byte, 16*sparseBlockSize)
+	zero := make([]byte, sparseBlockSize)
+	var off int64
+	for {
+		n, err := io.ReadFull(src, buf)
+		for start := 0; start < n; start += sparseBlockSize {
+			block := buf[start:min(start+sparseBlockSize, n)]
+			if !bytes.Equal(block, zero[:len(block)]) {
+				if _, err := dest.WriteAt(block, off+int64(start)); err != nil {
+					return errz.E(err, "write destination file")
+				}
+			}
+		}
+		off += int64(n)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return errz.E(err, "read source file")
+		}
+	}
+
+	// A trailing hole is never written, so set the length explicitly.
+	if err := dest.Truncate(off); err != nil {
+		return errz.E(err, "set destination size")
+	}
+	return dest.Close()
+}
+
+// SyncChanged copies p to dst like Copy, but only writes files that are
+// missing from dst or differ from their source, and returns how many files
+// were copied. A destination file of the same size and modification time is
+// taken as unchanged without reading it; when only the times differ, the
+// contents are compared by hash. Copied files get the permissions and
+// modification time of their source, so they take the fast path next time.
+// Unchanged files are not touched at all, and nothing is ever deleted from
+// dst. If p is a file and dst is a directory, p is synced into it under its
+// base name. Only regular files and directories are supported.
+func (p Path) SyncChanged(dst Path) (copied int, err error) {
+	fi, err := p.Stat()
+	if err != nil {
+		return 0, errz.E(err, "stat source").With("path", p)
+	}
+	if !fi.IsDir() {
+		if dst.IsDir() {
+			dst = dst.JoinPath(p.Base())
+		}
+		ok, err := syncFile(p, fi, dst)
+		if ok {
+			copied++
+		}
+		return copied, err
+	}
+
+	err = p.Walk(func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := Path(path).Rel(p)
+		if err != nil {
+			return err
+		}
+		target := dst.JoinPath(rel)
+
+		switch {
+		case d.IsDir():
+			return target.MkdirIfNotExist()
+		case d.Type().IsRegular():
+			fi, err := d.Info()
+			if err != nil {
+				return errz.E(err, "stat").With("path", path)
+			}
+			ok, err := syncFile(Path(path), fi, target)
+			if ok {
+				copied++
+			}
+			return err
+		default:
+			return errz.E("unsupported file type").With("path", path)
+		}
+	})
+	return copied, err
+}
+
+// syncFile copies src to dst unless dst already holds the same content, and
+// reports whether it copied.
+func syncFile(src Path, fi fs.FileInfo, dst Path) (bool, error) {
+	dfi, err := dst.Stat()
+	switch {
+	case err == nil:
+		if !dfi.Mode().IsRegular() {
+			return false, errz.E("destination is not a regular file").With("path", dst)
+		}
+		if dfi.Size() == fi.Size() {
+			if dfi.ModTime().Equal(fi.ModTime()) {
+				return false, nil
+			}
+			same, err := sameContent(src, dst)
+			if err != nil {
+				return false, err
+			}
+			if same {
+				return false, nil
+			}
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return false, errz.E(err, "stat destination").With("path", dst)
+	}
+
+	if err := dst.Dir().MkdirIfNotExist(); err != nil {
+		return false, errz.E(err, "create parent directory")
+	}
+	if err := copyFileContent(src, dst, fi.Mode().Perm()); err != nil {
+		return false, errz.E(err, "copy file").With("path", src)
+	}
+	if err := os.Chmod(string(dst), fi.Mode().Perm()); err != nil {
+		return true, errz.E(err, "set permissions").With("path", dst)
+	}
+	if err := os.Chtimes(string(dst), fi.ModTime(), fi.ModTime()); err != nil {
+		return true, errz.E(err, "set times").With("path", dst)
+	}
+	return true, nil
+}
+
+// sameContent reports whether a and b hold the same bytes, by hash.
+func sameContent(a, b Path) (bool, error) {
+	sumA, err := a.digest(sha256.New())
+	if err != nil {
+		return false, errz.E(err, "hash file").With("path", a)
+	}
+	sumB, err := b.digest(sha256.New())
+	if err != nil {
+		return false, errz.E(err, "hash file").With("path", b)
+	}
+	return sumA == sumB, nil
+}
+
+// samePrefix reports whether the first n bytes of src match the content of dst.
+func samePrefix(src io.Reader, dst Path, n int64) (bool, error) {
+	srcHash := sha256.New()
+	if _, err := io.CopyN(srcHash, src, n); err != nil {
+		return false, errz.E(err, "hash source prefix")
+	}
+
+	dstHash := sha256.New()
+	if _, err := dst.WriteTo(dstHash); err != nil {
+		return false, errz.E(err, "hash destination file")
+	}
+	return bytes.Equal(srcHash.Sum(nil), dstHash.Sum(nil)), nil
+}
+
+// renameOrCopy renames src to dst, falling back to copying the entry with its
+// permissions and modification times when they are on different devices. The
+// source is only removed once the copy has fully succeeded.
+func renameOrCopy(src, dst Path) error {
+	err := filesystem().Rename(string(src), string(dst))
+	if err == nil || !isCrossDevice(err) {
+		return err
+	}
+
+	if err := copyPreserving(src, dst); err != nil {
+		return errz.E(err, "copy across devices")
+	}
+	return src.Delete()
+}
+
+// copyPreserving copies src to dst recursively, keeping permissions and
+// modification times. Symlinks are recreated rather than followed.
+func copyPreserving(src, dst Path) error {
+	fi, err := os.Lstat(string(src))
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case fi.Mode()&fs.ModeSymlink != 0:
+		target, err := os.Readlink(string(src))
+		if err != nil {
+			return errz.E(err, "read symlink")
+		}
+		return os.Symlink(target, string(dst))
+
+	case fi.IsDir():
+		if err := os.Mkdir(string(dst), 0o700); err != nil {
+			return errz.E(err, "create directory")
+		}
+		entries, err := os.ReadDir(string(src))
+		if err != nil {
+			return errz.E(err, "read directory")
+		}
+		for _, e := range entries {
+			if err := copyPreserving(src.Join(e.Name()), dst.Join(e.Name())); err != nil {
+				return errz.E(err, "copy entry").With("name", e.Name())
+			}
+		}
+
+	case fi.Mode().IsRegular():
+		if err := copyFileContent(src, dst, fi.Mode().Perm()); err != nil {
+			return err
+		}
+
+	default:
+		return errz.E("unsupported file type").With("path", src).With("type", fi.Mode().Type())
+	}
+
+	if err := os.Chmod(string(dst), fi.Mode().Perm()); err != nil {
+		return errz.E(err, "set permissions")
+	}
+	_, _, accessed := src.Times()
+	if accessed.IsZero() {
+		accessed = fi.ModTime()
+	}
+	if err := os.Chtimes(string(dst), accessed, fi.ModTime()); err != nil {
+		return errz.E(err, "set times")
+	}
+	return nil
+}
+
+func copyFileContent(src, dst Path, perm fs.FileMode) error {
+	in, err := src.OpenHandle()
+	if err != nil {
+		return errz.E(err, "open source file")
+	}
+	defer in.Close()
+
+	out, err := filesystem().OpenFile(string(dst), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return errz.E(err, "open destination file")
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return errz.E(err, "copy content")
+	}
+	return out.Close()
+}
+
+// CopyFromFS writes the contents of src onto disk under dst, creating
+// directories as needed and overwriting existing files. Permissions are copied
+// from the modes src reports; entries reported without any permission bits get
+// the defaults set by SetDefaultFileMode and SetDefaultDirMode. Directory
+// permissions are applied after their contents are written, so read-only trees
+// such as an embed.FS can be copied. Every name is checked with fs.ValidPath
+// and converted with filepath.Localize, so no entry can be written outside
+// dst. To copy only a subdirectory, pass fs.Sub(src, dir). Only regular files
+// and directories are supported.
+func CopyFromFS(src fs.FS, dst Path) error {
+	type dirMode struct {
+		path Path
+		perm fs.FileMode
+	}
+	var dirs []dirMode
+
+	err := fs.WalkDir(src, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fs.ValidPath(name) {
+			return errz.E("invalid path").With("name", name)
+		}
+		local, err := filepath.Localize(name)
+		if err != nil {
+			return errz.E(err, "invalid path").With("name", name)
+		}
+		target := dst.Join(local)
+
+		fi, err := d.Info()
+		if err != nil {
+			return errz.E(err, "stat").With("name", name)
+		}
+		switch {
+		case fi.IsDir():
+			perm := fi.Mode().Perm()
+			if perm == 0 {
+				perm = defaultDirPerm()
+			}
+			if err := os.MkdirAll(string(target), 0o700); err != nil {
+				return errz.E(err, "create directory").With("path", target)
+			}
+			dirs = append(dirs, dirMode{target, perm})
+
+		case fi.Mode().IsRegular():
+			perm := fi.Mode().Perm()
+			if perm == 0 {
+				perm = defaultFilePerm()
+			}
+			if err := copyFromFSFile(src, name, target, perm); err != nil {
+				return errz.E(err, "copy file").With("name", name)
+			}
+
+		default:
+			return errz.E("unsupported file type").With("name", name).With("type", fi.Mode().Type())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Children come after their parent in walk order, so apply in reverse to
+	// keep directories writable until everything below them is done.
+	for _, d := range slices.Backward(dirs) {
+		if err := os.Chmod(string(d.path), d.perm); err != nil {
+			return errz.E(err, "set permissions").With("path", d.path)
+		}
+	}
+	return nil
+}
+
+func copyFromFSFile(src fs.FS, name string, dst Path, perm fs.FileMode) error {
+	in, err := src.Open(name)
+	if err != nil {
+		return errz.E(err, "open source file")
+	}
+	defer in.Close()
+
+	out, err := filesystem().OpenFile(string(dst), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return errz.E(err, "open destination file")
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return errz.E(err, "copy content")
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chmod(string(dst), perm)
+}
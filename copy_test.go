@@ -0,0 +1,500 @@
+package ppath
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestCopyResume(t *testing.T) {
+	tempDir := New(t.TempDir())
+	src := tempDir.Join("src.bin")
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	if err := src.WriteFile(content); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	check := func(t *testing.T, dst Path) {
+		t.Helper()
+		got, err := dst.ReadFile()
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		if !bytes.Equal(got, content) {
+			t.Errorf("expected %d bytes of source content, got %d bytes", len(content), len(got))
+		}
+	}
+
+	t.Run("Missing", func(t *testing.T) {
+		dst := tempDir.Join("missing.bin")
+		if err := src.CopyResume(dst); err != nil {
+			t.Fatalf("CopyResume: %v", err)
+		}
+		check(t, dst)
+	})
+
+	t.Run("Partial", func(t *testing.T) {
+		dst := tempDir.Join("partial.bin")
+		if err := dst.WriteFile(content[:4321]); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		if err := src.CopyResume(dst); err != nil {
+			t.Fatalf("CopyResume: %v", err)
+		}
+		check(t, dst)
+	})
+
+	t.Run("MismatchedPrefix", func(t *testing.T) {
+		dst := tempDir.Join("mismatch.bin")
+		if err := dst.WriteFile([]byte("garbage")); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		if err := src.CopyResume(dst); err != nil {
+			t.Fatalf("CopyResume: %v", err)
+		}
+		check(t, dst)
+	})
+
+	t.Run("Larger", func(t *testing.T) {
+		dst := tempDir.Join("larger.bin")
+		if err := dst.WriteFile(append(bytes.Clone(content), "extra"...)); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		if err := src.CopyResume(dst); err != nil {
+			t.Fatalf("CopyResume: %v", err)
+		}
+		check(t, dst)
+	})
+}
+
+func TestCopyPreserving(t *testing.T) {
+	tempDir := New(t.TempDir())
+	src := tempDir.Join("src")
+	if err := src.Join("sub", "file.txt").WriteFile(testContent); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.Chmod(src.Join("sub", "file.txt").String(), 0o600); err != nil {
+		t.Fatalf("os.Chmod: %v", err)
+	}
+	if err := os.Symlink("sub/file.txt", src.Join("link").String()); err != nil {
+		t.Fatalf("os.Symlink: %v", err)
+	}
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, p := range []Path{src.Join("sub", "file.txt"), src.Join("sub")} {
+		if err := os.Chtimes(p.String(), mtime, mtime); err != nil {
+			t.Fatalf("os.Chtimes: %v", err)
+		}
+	}
+
+	dst := tempDir.Join("dst")
+	if err := copyPreserving(src, dst); err != nil {
+		t.Fatalf("copyPreserving: %v", err)
+	}
+
+	fi, err := dst.Join("sub", "file.txt").Stat()
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if runtime.GOOS != "windows" && fi.Mode().Perm() != 0o600 {
+		t.Errorf("expected mode 0600, got %o", fi.Mode().Perm())
+	}
+	for _, p := range []Path{dst.Join("sub", "file.txt"), dst.Join("sub")} {
+		_, modified, _ := p.Times()
+		if !modified.Equal(mtime) {
+			t.Errorf("expected mtime %v for %s, got %v", mtime, p, modified)
+		}
+	}
+	if target, err := os.Readlink(dst.Join("link").String()); err != nil || target != "sub/file.txt" {
+		t.Errorf("expected link to sub/file.txt, got %q, error: %v", target, err)
+	}
+}
+
+func TestMergeMove_CrossDevice(t *testing.T) {
+	other := New("/dev/shm")
+	if same, err := New(t.TempDir()).SameVolume(other); err != nil || same || !other.IsWritable() {
+		t.Skip("no writable directory on another device")
+	}
+	otherDir, err := os.MkdirTemp(other.String(), "ppath")
+	if err != nil {
+		t.Fatalf("os.MkdirTemp: %v", err)
+	}
+	defer os.RemoveAll(otherDir)
+
+	src := New(otherDir).Join("src")
+	if err := src.Join("a.txt").WriteFile([]byte("a")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := src.Join("new", "b.txt").WriteFile([]byte("b")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	dst := New(t.TempDir()).Join("dst")
+	if err := dst.Join("a.txt").WriteFile([]byte("old")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	if err := src.MergeMove(dst); err != nil {
+		t.Fatalf("MergeMove: %v", err)
+	}
+	if src.Exists() {
+		t.Errorf("expected source directory to be deleted")
+	}
+	for name, expected := range map[string]string{"a.txt": "a", "new/b.txt": "b"} {
+		content, err := dst.Join(filepath.FromSlash(name)).ReadFile()
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		if string(content) != expected {
+			t.Errorf("expected %q for %s, got %q", expected, name, content)
+		}
+	}
+}
+
+func TestCopyWith(t *testing.T) {
+	old := time.Now().Add(-time.Hour)
+	setup := func(t *testing.T) (Path, Path) {
+		tempDir := New(t.TempDir())
+		src, dst := tempDir.Join("src"), tempDir.Join("dst")
+		if err := src.Join("sub", "a.txt").WriteFile([]byte("src a")); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		if err := src.Join("b.txt").WriteFile([]byte("src b")); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		if err := dst.Join("sub", "a.txt").WriteFile([]byte("dst a")); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		if err := os.Chtimes(dst.Join("sub", "a.txt").String(), old, old); err != nil {
+			t.Fatalf("os.Chtimes: %v", err)
+		}
+		return src, dst
+	}
+	check := func(t *testing.T, p Path, expected string) {
+		t.Helper()
+		content, err := p.ReadFile()
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		if string(content) != expected {
+			t.Errorf("expected %q in %s, got %q", expected, p, content)
+		}
+	}
+
+	t.Run("Overwrite", func(t *testing.T) {
+		src, dst := setup(t)
+		if err := src.CopyWith(dst, ConflictOverwrite); err != nil {
+			t.Fatalf("CopyWith: %v", err)
+		}
+		check(t, dst.Join("sub", "a.txt"), "src a")
+		check(t, dst.Join("b.txt"), "src b")
+		check(t, src.Join("sub", "a.txt"), "src a")
+	})
+
+	t.Run("Skip", func(t *testing.T) {
+		src, dst := setup(t)
+		if err := src.CopyWith(dst, ConflictSkip); err != nil {
+			t.Fatalf("CopyWith: %v", err)
+		}
+		check(t, dst.Join("sub", "a.txt"), "dst a")
+		check(t, dst.Join("b.txt"), "src b")
+	})
+
+	t.Run("KeepNewer", func(t *testing.T) {
+		src, dst := setup(t)
+		if err := src.CopyWith(dst, ConflictKeepNewer); err != nil {
+			t.Fatalf("CopyWith: %v", err)
+		}
+		check(t, dst.Join("sub", "a.txt"), "src a")
+	})
+
+	t.Run("Rename", func(t *testing.T) {
+		src, dst := setup(t)
+		if err := src.CopyWith(dst, ConflictRename); err != nil {
+			t.Fatalf("CopyWith: %v", err)
+		}
+		check(t, dst.Join("sub", "a.txt"), "dst a")
+		check(t, dst.Join("sub", "a (1).txt"), "src a")
+	})
+
+	t.Run("File", func(t *testing.T) {
+		src, dst := setup(t)
+		if err := src.Join("sub", "a.txt").CopyWith(dst.Join("sub"), ConflictSkip); err != nil {
+			t.Fatalf("CopyWith: %v", err)
+		}
+		check(t, dst.Join("sub", "a.txt"), "dst a")
+	})
+}
+
+func TestCopyFromFS(t *testing.T) {
+	src := fstest.MapFS{
+		"assets/app.js":        {Data: []byte("js"), Mode: 0o600},
+		"assets/img/logo.svg":  {Data: []byte("svg")},
+		"templates/index.html": {Data: []byte("html"), Mode: 0o444},
+		"templates":            {Mode: fs.ModeDir | 0o555},
+		"assets":               {Mode: fs.ModeDir | 0o750},
+		"assets/img":           {Mode: fs.ModeDir},
+	}
+	dst := New(t.TempDir(), "out")
+	// let the temporary directory cleanup remove the read-only directory
+	t.Cleanup(func() { os.Chmod(dst.Join("templates").String(), 0o755) })
+	if err := CopyFromFS(src, dst); err != nil {
+		t.Fatalf("CopyFromFS: %v", err)
+	}
+
+	for name, f := range src {
+		if f.Mode.IsDir() {
+			continue
+		}
+		content, err := dst.Join(name).ReadFile()
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		if string(content) != string(f.Data) {
+			t.Errorf("expected %s, got %s", f.Data, content)
+		}
+	}
+	if runtime.GOOS != "windows" {
+		for name, expected := range map[string]fs.FileMode{
+			"assets/app.js":        0o600,
+			"assets/img/logo.svg":  0o644,
+			"templates/index.html": 0o444,
+			"templates":            0o555,
+			"assets":               0o750,
+			"assets/img":           0o755,
+		} {
+			fi, err := dst.Join(name).Stat()
+			if err != nil {
+				t.Fatalf("Stat: %v", err)
+			}
+			if fi.Mode().Perm() != expected {
+				t.Errorf("expected mode %o for %s, got %o", expected, name, fi.Mode().Perm())
+			}
+		}
+	}
+
+	t.Run("Subdirectory", func(t *testing.T) {
+		sub, err := fs.Sub(src, "assets")
+		if err != nil {
+			t.Fatalf("fs.Sub: %v", err)
+		}
+		dst := New(t.TempDir())
+		if err := CopyFromFS(sub, dst); err != nil {
+			t.Fatalf("CopyFromFS: %v", err)
+		}
+		if !dst.Join("app.js").IsRegular() || !dst.Join("img", "logo.svg").IsRegular() {
+			t.Errorf("expected subdirectory contents at the destination root")
+		}
+		if dst.Join("templates").Exists() {
+			t.Errorf("expected entries outside the subdirectory to be skipped")
+		}
+	})
+
+	t.Run("Overwrite", func(t *testing.T) {
+		dst := New(t.TempDir())
+		t.Cleanup(func() { os.Chmod(dst.Join("templates").String(), 0o755) })
+		if err := dst.Join("assets", "app.js").WriteFile([]byte("stale content")); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		if err := CopyFromFS(src, dst); err != nil {
+			t.Fatalf("CopyFromFS: %v", err)
+		}
+		if content, _ := dst.Join("assets", "app.js").ReadFile(); string(content) != "js" {
+			t.Errorf("expected js, got %s", content)
+		}
+	})
+}
+
+func TestCopyContents(t *testing.T) {
+	tempDir := New(t.TempDir())
+	src, dst := tempDir.Join("src"), tempDir.Join("dst")
+	if err := src.Join("sub", "a.txt").WriteFile([]byte("src a")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := src.Join("run.sh").WriteFile([]byte("src run")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.Chmod(src.Join("run.sh").String(), 0o750); err != nil {
+		t.Fatalf("os.Chmod: %v", err)
+	}
+	if err := os.Chmod(src.Join("sub").String(), 0o700); err != nil {
+		t.Fatalf("os.Chmod: %v", err)
+	}
+	if err := dst.Join("keep.txt").WriteFile([]byte("dst keep")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := dst.Join("sub", "a.txt").WriteFile([]byte("dst a")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	if err := src.CopyContents(dst); err != nil {
+		t.Fatalf("CopyContents: %v", err)
+	}
+	for name, expected := range map[string]string{"sub/a.txt": "src a", "run.sh": "src run", "keep.txt": "dst keep"} {
+		if content, err := dst.Join(name).ReadFile(); err != nil || string(content) != expected {
+			t.Errorf("expected %q in %s, got %q, error: %v", expected, name, content, err)
+		}
+	}
+	if dst.Join("src").Exists() {
+		t.Errorf("expected no nested source directory")
+	}
+	if runtime.GOOS != "windows" {
+		for name, expected := range map[string]fs.FileMode{"run.sh": 0o750, "sub": 0o700} {
+			fi, err := dst.Join(name).Stat()
+			if err != nil {
+				t.Fatalf("Stat: %v", err)
+			}
+			if fi.Mode().Perm() != expected {
+				t.Errorf("expected mode %o for %s, got %o", expected, name, fi.Mode().Perm())
+			}
+		}
+	}
+
+	if err := src.CopyContentsWith(tempDir.Join("fresh"), ConflictSkip); err != nil {
+		t.Fatalf("CopyContentsWith: %v", err)
+	}
+	if !tempDir.Join("fresh", "sub", "a.txt").IsRegular() {
+		t.Errorf("expected destination to be created")
+	}
+	if err := dst.Join("sub", "a.txt").WriteFile([]byte("dst a")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := src.CopyContentsWith(dst, ConflictSkip); err != nil {
+		t.Fatalf("CopyContentsWith: %v", err)
+	}
+	if content, _ := dst.Join("sub", "a.txt").ReadFile(); string(content) != "dst a" {
+		t.Errorf("expected existing file to be kept, got %q", content)
+	}
+
+	if err := src.Join("run.sh").CopyContents(dst); err == nil {
+		t.Errorf("expected error for a file source, got nil")
+	}
+}
+
+func TestSyncChanged(t *testing.T) {
+	tempDir := New(t.TempDir())
+	src, dst := tempDir.Join("src"), tempDir.Join("dst")
+	for name, content := range map[string]string{"a.txt": "a", "sub/b.txt": "b", "sub/c.txt": "c"} {
+		if err := src.Join(name).WriteFile([]byte(content)); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	copied, err := src.SyncChanged(dst)
+	if err != nil {
+		t.Fatalf("SyncChanged: %v", err)
+	}
+	if copied != 3 {
+		t.Errorf("expected 3 files copied, got %d", copied)
+	}
+
+	copied, err = src.SyncChanged(dst)
+	if err != nil || copied != 0 {
+		t.Errorf("expected nothing copied on a second sync, got %d, error: %v", copied, err)
+	}
+
+	// Same content with a different mtime is detected by hash and left alone.
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(dst.Join("a.txt").String(), old, old); err != nil {
+		t.Fatalf("os.Chtimes: %v", err)
+	}
+	// Same size but different content must be copied.
+	if err := src.Join("sub", "b.txt").WriteFile([]byte("B")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	newer := time.Now().Add(time.Hour)
+	if err := os.Chtimes(src.Join("sub", "b.txt").String(), newer, newer); err != nil {
+		t.Fatalf("os.Chtimes: %v", err)
+	}
+	if err := dst.Join("extra.txt").WriteFile([]byte("extra")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	copied, err = src.SyncChanged(dst)
+	if err != nil {
+		t.Fatalf("SyncChanged: %v", err)
+	}
+	if copied != 1 {
+		t.Errorf("expected 1 file copied, got %d", copied)
+	}
+	if content, _ := dst.Join("sub", "b.txt").ReadFile(); string(content) != "B" {
+		t.Errorf("expected B, got %q", content)
+	}
+	if _, modified, _ := dst.Join("a.txt").Times(); !modified.Equal(old) {
+		t.Errorf("expected unchanged file to keep mtime %v, got %v", old, modified)
+	}
+	if !dst.Join("extra.txt").Exists() {
+		t.Errorf("expected extra destination file to be kept")
+	}
+
+	copied, err = src.Join("a.txt").SyncChanged(tempDir.Join("single"))
+	if err != nil || copied != 1 {
+		t.Errorf("expected single file copied, got %d, error: %v", copied, err)
+	}
+}
+
+func TestCopySparse(t *testing.T) {
+	tempDir := New(t.TempDir())
+	tests := []struct {
+		name  string
+		size  int64
+		chunk map[int64]string
+	}{
+		{"empty", 0, nil},
+		{"dense", 10, map[int64]string{0: "0123456789"}},
+		{"hole in the middle", 1 << 20, map[int64]string{0: "head", 1<<20 - 4: "tail"}},
+		{"trailing hole", 1 << 20, map[int64]string{100: "data"}},
+		{"unaligned", 3*sparseBlockSize + 17, map[int64]string{sparseBlockSize - 2: "spans blocks"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := tempDir.Join(tt.name + ".img")
+			f, err := src.Create()
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			if err := f.Truncate(tt.size); err != nil {
+				t.Fatalf("Truncate: %v", err)
+			}
+			for off, s := range tt.chunk {
+				if _, err := f.WriteAt([]byte(s), off); err != nil {
+					t.Fatalf("WriteAt: %v", err)
+				}
+			}
+			f.Close()
+
+			dst := tempDir.Join("copies", tt.name+".img")
+			if err := src.CopySparse(dst); err != nil {
+				t.Fatalf("CopySparse: %v", err)
+			}
+			want, _ := src.ReadFile()
+			got, err := dst.ReadFile()
+			if err != nil {
+				t.Fatalf("ReadFile: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("expected copy to be byte-identical (%d bytes), got %d bytes", len(want), len(got))
+			}
+		})
+	}
+
+	// Copying over a larger file must not leave its tail behind.
+	src, dst := tempDir.Join("small"), tempDir.Join("large")
+	if err := src.WriteFile([]byte("small")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := dst.WriteFile(bytes.Repeat([]byte("x"), 10000)); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := src.CopySparse(dst); err != nil {
+		t.Fatalf("CopySparse: %v", err)
+	}
+	if got, _ := dst.ReadFile(); string(got) != "small" {
+		t.Errorf("expected small, got %d bytes", len(got))
+	}
+}
@@ -0,0 +1,174 @@
+package ppath
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/maa3x/errz"
+)
+
+// TreeDiff lists the differences between two trees as paths relative to
+// their roots. Added and Removed describe what turns the first tree into the
+// second: Added entries exist only in the second tree and Removed entries only
+// in the first. An entry missing on one side is listed once, without the
+// contents of a missing directory. Modified entries exist in both trees but
+// differ in type or content. Every list is sorted.
+type TreeDiff struct {
+	Added    Paths
+	Removed  Paths
+	Modified Paths
+}
+
+// Empty reports whether the trees have no differences.
+func (d TreeDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0
+}
+
+// DiffOptions configures DiffTree. The zero value compares files by size and
+// modification time and ignores symlinks.
+type DiffOptions struct {
+	// CompareContent compares files of equal size by hashing their content
+	// instead of by modification time.
+	CompareContent bool
+	// CompareSymlinks includes symlinks, which are compared by their target
+	// and never followed.
+	CompareSymlinks bool
+}
+
+// DiffOption configures DiffTree.
+type DiffOption func(*DiffOptions)
+
+// DiffContent makes DiffTree compare files by content.
+func DiffContent() DiffOption {
+	return func(o *DiffOptions) { o.CompareContent = true }
+}
+
+// DiffSymlinks makes DiffTree compare symlinks by target.
+func DiffSymlinks() DiffOption {
+	return func(o *DiffOptions) { o.CompareSymlinks = true }
+}
+
+// DiffTree compares the tree rooted at p with the one rooted at other and
+// reports their differences without changing either. Files are modified when
+// their sizes differ or, by default, their modification times differ;
+// directories present in both trees are never modified themselves. An entry
+// that is a directory in one tree and something else in the other is modified,
+// and the directory's contents are not listed. Special files other than
+// symlinks are ignored.
+func (p Path) DiffTree(other Path, opts ...DiffOption) (TreeDiff, error) {
+	var o DiffOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	left, err := p.diffScan(o)
+	if err != nil {
+		return TreeDiff{}, err
+	}
+	right, err := other.diffScan(o)
+	if err != nil {
+		return TreeDiff{}, err
+	}
+
+	var d TreeDiff
+	for rel, lfi := range left {
+		rfi, ok := right[rel]
+		if !ok {
+			if !parentMissing(rel, right) {
+				d.Removed = append(d.Removed, Path(rel))
+			}
+			continue
+		}
+		modified, err := diffEntry(p.Join(rel), lfi, other.Join(rel), rfi, o)
+		if err != nil {
+			return TreeDiff{}, err
+		}
+		if modified {
+			d.Modified = append(d.Modified, Path(rel))
+		}
+	}
+	for rel := range right {
+		if _, ok := left[rel]; !ok && !parentMissing(rel, left) {
+			d.Added = append(d.Added, Path(rel))
+		}
+	}
+
+	d.Added.Sort()
+	d.Removed.Sort()
+	d.Modified.Sort()
+	return d, nil
+}
+
+// diffScan records the entries below p by relative path.
+func (p Path) diffScan(o DiffOptions) (map[string]fs.FileInfo, error) {
+	entries := make(map[string]fs.FileInfo)
+	err := p.Walk(func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == string(p) {
+			if !d.IsDir() {
+				return errz.E("not a directory").With("path", p)
+			}
+			return nil
+		}
+		if t := d.Type(); !t.IsDir() && !t.IsRegular() && (t&fs.ModeSymlink == 0 || !o.CompareSymlinks) {
+			return nil
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			return errz.E(err, "stat").With("path", path)
+		}
+		rel, err := filepath.Rel(string(p), path)
+		if err != nil {
+			return err
+		}
+		entries[rel] = fi
+		return nil
+	})
+	if err != nil {
+		return nil, errz.E(err, "scan tree").With("path", p)
+	}
+	return entries, nil
+}
+
+// parentMissing reports whether the directory containing rel is missing from
+// entries or is something else there, in which case that directory is
+// reported instead of rel.
+func parentMissing(rel string, entries map[string]fs.FileInfo) bool {
+	parent := filepath.Dir(rel)
+	if parent == "." {
+		return false
+	}
+	fi, ok := entries[parent]
+	return !ok || !fi.IsDir()
+}
+
+func diffEntry(a Path, afi fs.FileInfo, b Path, bfi fs.FileInfo, o DiffOptions) (bool, error) {
+	if afi.Mode().Type() != bfi.Mode().Type() {
+		return true, nil
+	}
+	switch {
+	case afi.IsDir():
+		return false, nil
+	case afi.Mode()&fs.ModeSymlink != 0:
+		at, err := os.Readlink(string(a))
+		if err != nil {
+			return false, errz.E(err, "read symlink").With("path", a)
+		}
+		bt, err := os.Readlink(string(b))
+		if err != nil {
+			return false, errz.E(err, "read symlink").With("path", b)
+		}
+		return at != bt, nil
+	case afi.Size() != bfi.Size():
+		return true, nil
+	case o.CompareContent:
+		same, err := sameContent(a, b)
+		return !same, err
+	default:
+		return !afi.ModTime().Equal(bfi.ModTime()), nil
+	}
+}
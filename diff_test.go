@@ -0,0 +1,102 @@
+package ppath
+
+import (
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDiffTree(t *testing.T) {
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	build := func(t *testing.T, files map[string]string) Path {
+		root := New(t.TempDir())
+		for name, content := range files {
+			p := root.Join(name)
+			if err := p.WriteFile([]byte(content)); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+			if err := os.Chtimes(p.String(), mtime, mtime); err != nil {
+				t.Fatalf("os.Chtimes: %v", err)
+			}
+		}
+		return root
+	}
+
+	src := build(t, map[string]string{
+		"same.txt":      "same",
+		"grown.txt":     "a",
+		"touched.txt":   "abc",
+		"gone.txt":      "x",
+		"olddir/a.txt":  "a",
+		"olddir/b.txt":  "b",
+		"kind/file.txt": "f",
+	})
+	dst := build(t, map[string]string{
+		"same.txt":         "same",
+		"grown.txt":        "ab",
+		"touched.txt":      "xyz",
+		"new.txt":          "n",
+		"newdir/deep/c.go": "c",
+		"kind":             "now a file",
+	})
+	later := mtime.Add(time.Hour)
+	if err := os.Chtimes(dst.Join("touched.txt").String(), later, later); err != nil {
+		t.Fatalf("os.Chtimes: %v", err)
+	}
+
+	d, err := src.DiffTree(dst)
+	if err != nil {
+		t.Fatalf("DiffTree: %v", err)
+	}
+	check := func(name string, got, expected Paths) {
+		t.Helper()
+		if !slices.Equal(got, expected) {
+			t.Errorf("%s: expected %v, got %v", name, expected, got)
+		}
+	}
+	check("Added", d.Added, Paths{"new.txt", "newdir"})
+	check("Removed", d.Removed, Paths{"gone.txt", "olddir"})
+	check("Modified", d.Modified, Paths{"grown.txt", "kind", "touched.txt"})
+
+	// Same size and time but different content is only seen by hashing.
+	if err := os.Chtimes(dst.Join("touched.txt").String(), mtime, mtime); err != nil {
+		t.Fatalf("os.Chtimes: %v", err)
+	}
+	if d, _ := src.DiffTree(dst); slices.Contains(d.Modified, "touched.txt") {
+		t.Errorf("expected touched.txt to look unchanged by size and time")
+	}
+	if d, _ := src.DiffTree(dst, DiffContent()); !slices.Contains(d.Modified, "touched.txt") {
+		t.Errorf("expected touched.txt to differ by content, got %v", d.Modified)
+	}
+
+	if d, err := src.DiffTree(src); err != nil || !d.Empty() {
+		t.Errorf("expected no differences, got %+v, error: %v", d, err)
+	}
+	if _, err := src.DiffTree(src.Join("missing")); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
+func TestDiffTreeSymlinks(t *testing.T) {
+	a, b := New(t.TempDir()), New(t.TempDir())
+	for root, target := range map[Path]string{a: "one", b: "two"} {
+		if err := os.Symlink(target, root.Join("link").String()); err != nil {
+			t.Skipf("os.Symlink: %v", err)
+		}
+	}
+	if err := os.Symlink("x", a.Join("only").String()); err != nil {
+		t.Fatalf("os.Symlink: %v", err)
+	}
+
+	if d, err := a.DiffTree(b); err != nil || !d.Empty() {
+		t.Errorf("expected symlinks to be ignored, got %+v, error: %v", d, err)
+	}
+	d, err := a.DiffTree(b, DiffSymlinks())
+	if err != nil {
+		t.Fatalf("DiffTree: %v", err)
+	}
+	if !slices.Equal(d.Modified, Paths{"link"}) || !slices.Equal(d.Removed, Paths{"only"}) {
+		t.Errorf("expected link modified and only removed, got %+v", d)
+	}
+}
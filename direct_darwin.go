@@ -0,0 +1,24 @@
+//go:build darwin
+
+package ppath
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// openDirect turns off caching with F_NOCACHE, which unlike O_DIRECT has no
+// alignment requirements.
+func openDirect(path string, flag int) (*os.File, error) {
+	f, err := os.OpenFile(path, flag, defaultFilePerm())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := unix.FcntlInt(f.Fd(), unix.F_NOCACHE, 1); err != nil {
+		f.Close()
+		return nil, errors.Join(errors.ErrUnsupported, err)
+	}
+	return f, nil
+}
@@ -0,0 +1,18 @@
+//go:build linux
+
+package ppath
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+func openDirect(path string, flag int) (*os.File, error) {
+	f, err := os.OpenFile(path, flag|syscall.O_DIRECT, defaultFilePerm())
+	// Filesystems without direct I/O, such as tmpfs, reject the flag.
+	if errors.Is(err, syscall.EINVAL) {
+		return nil, errors.Join(errors.ErrUnsupported, err)
+	}
+	return f, err
+}
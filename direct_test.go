@@ -0,0 +1,57 @@
+package ppath
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+	"unsafe"
+)
+
+func TestAlignedBuffer(t *testing.T) {
+	for _, size := range []int{0, 1, DirectAlignment, 3*DirectAlignment + 5} {
+		buf := AlignedBuffer(size)
+		if len(buf) != size || cap(buf) != size {
+			t.Errorf("expected len and cap %d, got %d and %d", size, len(buf), cap(buf))
+		}
+		if size > 0 && uintptr(unsafe.Pointer(&buf[0]))%DirectAlignment != 0 {
+			t.Errorf("expected buffer of size %d to be aligned", size)
+		}
+	}
+}
+
+func TestCopyDirect(t *testing.T) {
+	tempDir := New(t.TempDir())
+	probe, err := tempDir.Join("probe").OpenDirect(os.O_WRONLY | os.O_CREATE)
+	if errors.Is(err, errors.ErrUnsupported) {
+		t.Skipf("direct I/O not supported: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("OpenDirect: %v", err)
+	}
+	probe.Close()
+
+	for _, size := range []int{0, 100, DirectAlignment, directChunk, directChunk + 3*DirectAlignment + 7} {
+		content := bytes.Repeat([]byte("0123456789abcdef"), size/16+1)[:size]
+		src := tempDir.Join("src.bin")
+		if err := src.WriteFile(content); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+
+		dst := tempDir.Join("out", "dst.bin")
+		if err := src.CopyDirect(dst); err != nil {
+			t.Fatalf("CopyDirect(%d bytes): %v", size, err)
+		}
+		got, err := dst.ReadFile()
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		if !bytes.Equal(got, content) {
+			t.Errorf("expected %d identical bytes, got %d", size, len(got))
+		}
+	}
+
+	if err := tempDir.Join("missing").CopyDirect(tempDir.Join("x")); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
@@ -0,0 +1,55 @@
+//go:build windows
+
+package ppath
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// openDirect maps flag onto CreateFile, since os.OpenFile cannot pass
+// FILE_FLAG_NO_BUFFERING. Appending is not supported, as unbuffered writes
+// must start at aligned offsets.
+func openDirect(path string, flag int) (*os.File, error) {
+	if flag&os.O_APPEND != 0 {
+		return nil, &os.PathError{Op: "open", Path: path, Err: errors.ErrUnsupported}
+	}
+
+	var access uint32
+	switch flag & (os.O_RDONLY | os.O_WRONLY | os.O_RDWR) {
+	case os.O_RDONLY:
+		access = windows.GENERIC_READ
+	case os.O_WRONLY:
+		access = windows.GENERIC_WRITE
+	default:
+		access = windows.GENERIC_READ | windows.GENERIC_WRITE
+	}
+
+	var disposition uint32
+	switch {
+	case flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL:
+		disposition = windows.CREATE_NEW
+	case flag&(os.O_CREATE|os.O_TRUNC) == os.O_CREATE|os.O_TRUNC:
+		disposition = windows.CREATE_ALWAYS
+	case flag&os.O_CREATE != 0:
+		disposition = windows.OPEN_ALWAYS
+	case flag&os.O_TRUNC != 0:
+		disposition = windows.TRUNCATE_EXISTING
+	default:
+		disposition = windows.OPEN_EXISTING
+	}
+
+	name, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	const share = windows.FILE_SHARE_READ | windows.FILE_SHARE_WRITE | windows.FILE_SHARE_DELETE
+	h, err := windows.CreateFile(name, access, share, nil, disposition,
+		windows.FILE_ATTRIBUTE_NORMAL|windows.FILE_FLAG_NO_BUFFERING, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	return os.NewFile(uintptr(h), path), nil
+}
@@ -0,0 +1,107 @@
+package ppath
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FS returns a read-only fs.FS for the tree rooted at p. Besides Open it
+// implements fs.StatFS, fs.ReadFileFS, fs.ReadDirFS and fs.SubFS, so consumers
+// such as fs.Glob, fs.WalkDir and http.FileServerFS take their fast paths.
+// Names must satisfy fs.ValidPath and are converted with filepath.Localize, so
+// no name can refer to a file outside p; symlinks inside the tree are still
+// followed. Like os.DirFS it always uses the real OS filesystem, and errors
+// carry the name as passed rather than the OS path.
+func (p Path) FS() fs.FS {
+	return dirFS{root: p}
+}
+
+type dirFS struct {
+	root Path
+}
+
+var (
+	_ fs.StatFS     = dirFS{}
+	_ fs.ReadFileFS = dirFS{}
+	_ fs.ReadDirFS  = dirFS{}
+	_ fs.SubFS      = dirFS{}
+)
+
+// join validates name and returns the OS path it refers to.
+func (d dirFS) join(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	local, err := filepath.Localize(name)
+	if err != nil {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return filepath.Join(string(d.root), local), nil
+}
+
+// fsError replaces the OS path in err with the name used in the fs.FS.
+func fsError(err error, name string) error {
+	var pe *fs.PathError
+	if errors.As(err, &pe) {
+		pe.Path = name
+	}
+	return err
+}
+
+func (d dirFS) Open(name string) (fs.File, error) {
+	path, err := d.join("open", name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fsError(err, name)
+	}
+	return f, nil
+}
+
+func (d dirFS) Stat(name string) (fs.FileInfo, error) {
+	path, err := d.join("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, fsError(err, name)
+	}
+	return fi, nil
+}
+
+func (d dirFS) ReadFile(name string) ([]byte, error) {
+	path, err := d.join("readfile", name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fsError(err, name)
+	}
+	return data, nil
+}
+
+func (d dirFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	path, err := d.join("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fsError(err, name)
+	}
+	return entries, nil
+}
+
+func (d dirFS) Sub(dir string) (fs.FS, error) {
+	path, err := d.join("sub", dir)
+	if err != nil {
+		return nil, err
+	}
+	return dirFS{root: Path(path)}, nil
+}
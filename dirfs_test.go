@@ -0,0 +1,54 @@
+package ppath
+
+import (
+	"errors"
+	"io/fs"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFS(t *testing.T) {
+	root := New(t.TempDir())
+	for _, name := range []string{"a.txt", "dir/b.txt", "dir/sub/c.go", "empty/.keep"} {
+		if err := root.Join(name).WriteFile([]byte(name)); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	fsys := root.FS()
+	if err := fstest.TestFS(fsys, "a.txt", "dir/b.txt", "dir/sub/c.go", "empty/.keep"); err != nil {
+		t.Fatalf("TestFS: %v", err)
+	}
+
+	sub, err := fs.Sub(fsys, "dir")
+	if err != nil {
+		t.Fatalf("Sub: %v", err)
+	}
+	if _, ok := sub.(dirFS); !ok {
+		t.Errorf("expected Sub to return a dirFS, got %T", sub)
+	}
+	if err := fstest.TestFS(sub, "b.txt", "sub/c.go"); err != nil {
+		t.Fatalf("TestFS on Sub: %v", err)
+	}
+
+	matches, err := fs.Glob(fsys, "dir/*/*.go")
+	if err != nil || !slices.Equal(matches, []string{"dir/sub/c.go"}) {
+		t.Errorf("expected [dir/sub/c.go], got %v, error: %v", matches, err)
+	}
+
+	for _, name := range []string{"../a.txt", "/a.txt", "dir/../a.txt", "", `dir\b.txt`} {
+		if _, err := fs.ReadFile(fsys, name); !errors.Is(err, fs.ErrInvalid) && !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("ReadFile(%q): expected an invalid or missing path, got %v", name, err)
+		}
+	}
+	if _, err := fs.Sub(fsys, "../x"); err == nil {
+		t.Errorf("expected error for an invalid Sub directory, got nil")
+	}
+
+	_, err = fs.Stat(fsys, "missing.txt")
+	var pe *fs.PathError
+	if !errors.As(err, &pe) || pe.Path != "missing.txt" || !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a not-exist error naming missing.txt, got %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package ppath
+
+import (
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/maa3x/errz"
+)
+
+// DirHandle is an open directory. Listing it in batches and stating entries
+// relative to it avoids re-resolving the directory path for every entry, so
+// the entries seen all belong to the directory that was opened even if its
+// path is renamed or replaced meanwhile.
+type DirHandle struct {
+	path Path
+	f    *os.File
+}
+
+// OpenDir opens the directory p for listing. Close the handle when done.
+func (p Path) OpenDir() (*DirHandle, error) {
+	f, err := os.Open(string(p))
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, errz.E(err, "stat directory").With("path", p)
+	}
+	if !fi.IsDir() {
+		f.Close()
+		return nil, errz.E("not a directory").With("path", p)
+	}
+	return &DirHandle{path: p, f: f}, nil
+}
+
+// Path returns the path the directory was opened with.
+func (d *DirHandle) Path() Path {
+	return d.path
+}
+
+// Names returns the names of up to n further entries, in directory order, as
+// with os.File.Readdirnames. With n > 0 it returns io.EOF once the directory
+// is exhausted; with n <= 0 it returns all remaining names.
+func (d *DirHandle) Names(n int) ([]string, error) {
+	return d.f.Readdirnames(n)
+}
+
+// Entries returns up to n further entries, in directory order, as with
+// os.File.ReadDir. With n > 0 it returns io.EOF once the directory is
+// exhausted; with n <= 0 it returns all remaining entries.
+func (d *DirHandle) Entries(n int) ([]fs.DirEntry, error) {
+	return d.f.ReadDir(n)
+}
+
+// Stat returns the FileInfo for the entry name of the directory, following
+// symlinks as os.Stat does. The entry is resolved relative to the open
+// directory, with fstatat on Unix and NtCreateFile on Windows. name must be a
+// single path segment.
+func (d *DirHandle) Stat(name string) (fs.FileInfo, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/`+string(os.PathSeparator)) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	return statAt(d.f, name)
+}
+
+// Close closes the directory.
+func (d *DirHandle) Close() error {
+	return d.f.Close()
+}
@@ -0,0 +1,128 @@
+package ppath
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestOpenDir(t *testing.T) {
+	tempDir := New(t.TempDir())
+	dir := tempDir.Join("dir")
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		if err := dir.Join(name).WriteFile([]byte(name)); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	if err := dir.Join("sub").MkdirIfNotExist(); err != nil {
+		t.Fatalf("MkdirIfNotExist: %v", err)
+	}
+
+	d, err := dir.OpenDir()
+	if err != nil {
+		t.Fatalf("OpenDir: %v", err)
+	}
+	defer d.Close()
+
+	var names []string
+	for {
+		batch, err := d.Names(2)
+		names = append(names, batch...)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Names: %v", err)
+		}
+	}
+	slices.Sort(names)
+	if expected := []string{"a.txt", "b.txt", "c.txt", "sub"}; !slices.Equal(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+
+	// Entries are resolved against the opened directory, not its path.
+	opened := dir
+	if runtime.GOOS != "windows" {
+		moved := tempDir.Join("moved")
+		opened = moved
+		if err := os.Rename(dir.String(), moved.String()); err != nil {
+			t.Fatalf("Rename: %v", err)
+		}
+		if err := dir.Join("a.txt").WriteFile([]byte("a replacement")); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	fi, err := d.Stat("a.txt")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if fi.Name() != "a.txt" || fi.Size() != int64(len("a.txt")) || !fi.Mode().IsRegular() {
+		t.Errorf("unexpected info for a.txt: name %s, size %d, mode %v", fi.Name(), fi.Size(), fi.Mode())
+	}
+	expectedID, err := opened.Join("a.txt").Identity()
+	if err != nil {
+		t.Fatalf("Identity: %v", err)
+	}
+	if id, ok := infoID(fi); !ok || id != expectedID {
+		t.Errorf("expected file id %v, got %v (ok %v)", expectedID, id, ok)
+	}
+	if fi, err := d.Stat("sub"); err != nil || !fi.IsDir() || fi.Mode()&fs.ModeDir == 0 {
+		t.Errorf("expected sub to be a directory, got %v, error: %v", fi, err)
+	}
+
+	for _, name := range []string{"", ".", "..", "sub/x", "missing"} {
+		if _, err := d.Stat(name); err == nil {
+			t.Errorf("Stat(%q): expected error, got nil", name)
+		}
+	}
+	if _, err := d.Stat(".."); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("expected fs.ErrInvalid, got %v", err)
+	}
+
+	if _, err := tempDir.Join("missing").OpenDir(); err == nil {
+		t.Errorf("expected error for a missing directory, got nil")
+	}
+	if _, err := dir.Join("a.txt").OpenDir(); err == nil {
+		t.Errorf("expected error for a file, got nil")
+	}
+}
+
+func TestOpenDirEntries(t *testing.T) {
+	dir := New(t.TempDir())
+	for _, name := range []string{"x", "y"} {
+		if err := dir.Join(name).WriteFile(nil); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	if err := os.Symlink("x", dir.Join("link").String()); err != nil {
+		t.Skipf("os.Symlink: %v", err)
+	}
+
+	d, err := dir.OpenDir()
+	if err != nil {
+		t.Fatalf("OpenDir: %v", err)
+	}
+	defer d.Close()
+
+	entries, err := d.Entries(-1)
+	if err != nil {
+		t.Fatalf("Entries: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	for _, e := range entries {
+		fi, err := d.Stat(e.Name())
+		if err != nil {
+			t.Fatalf("Stat(%s): %v", e.Name(), err)
+		}
+		if !fi.Mode().IsRegular() {
+			t.Errorf("expected %s to resolve to a regular file, got %v", e.Name(), fi.Mode())
+		}
+	}
+}
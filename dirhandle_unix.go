@@ -0,0 +1,68 @@
+//go:build linux || darwin
+
+package ppath
+
+import (
+	"io/fs"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func statAt(dir *os.File, name string) (fs.FileInfo, error) {
+	var st unix.Stat_t
+	if err := ignoringEINTR(func() error { return unix.Fstatat(int(dir.Fd()), name, &st, 0) }); err != nil {
+		return nil, &fs.PathError{Op: "fstatat", Path: name, Err: err}
+	}
+	return &statInfo{name: name, st: st}, nil
+}
+
+func ignoringEINTR(fn func() error) error {
+	for {
+		if err := fn(); err != unix.EINTR {
+			return err
+		}
+	}
+}
+
+// statInfo is the fs.FileInfo for a unix.Stat_t, which Sys returns.
+type statInfo struct {
+	name string
+	st   unix.Stat_t
+}
+
+func (s *statInfo) Name() string       { return s.name }
+func (s *statInfo) Size() int64        { return s.st.Size }
+func (s *statInfo) IsDir() bool        { return s.Mode().IsDir() }
+func (s *statInfo) Sys() any           { return &s.st }
+func (s *statInfo) ModTime() time.Time { return time.Unix(s.st.Mtim.Unix()) }
+
+func (s *statInfo) Mode() fs.FileMode {
+	raw := uint32(s.st.Mode) //nolint:unconvert // Mode is uint16 on darwin
+	mode := fs.FileMode(raw & 0o777)
+	switch raw & unix.S_IFMT {
+	case unix.S_IFBLK:
+		mode |= fs.ModeDevice
+	case unix.S_IFCHR:
+		mode |= fs.ModeDevice | fs.ModeCharDevice
+	case unix.S_IFDIR:
+		mode |= fs.ModeDir
+	case unix.S_IFIFO:
+		mode |= fs.ModeNamedPipe
+	case unix.S_IFLNK:
+		mode |= fs.ModeSymlink
+	case unix.S_IFSOCK:
+		mode |= fs.ModeSocket
+	}
+	if raw&unix.S_ISGID != 0 {
+		mode |= fs.ModeSetgid
+	}
+	if raw&unix.S_ISUID != 0 {
+		mode |= fs.ModeSetuid
+	}
+	if raw&unix.S_ISVTX != 0 {
+		mode |= fs.ModeSticky
+	}
+	return mode
+}
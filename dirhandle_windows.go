@@ -0,0 +1,79 @@
+//go:build windows
+
+package ppath
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// statAt opens name relative to the directory handle with NtCreateFile, the
+// only Windows API that takes a root directory, and reads its information
+// from the resulting handle. Symlinks are followed.
+func statAt(dir *os.File, name string) (fs.FileInfo, error) {
+	objName, err := windows.NewNTUnicodeString(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	oa := &windows.OBJECT_ATTRIBUTES{
+		RootDirectory: windows.Handle(dir.Fd()),
+		ObjectName:    objName,
+		Attributes:    windows.OBJ_CASE_INSENSITIVE,
+	}
+	oa.Length = uint32(unsafe.Sizeof(*oa))
+
+	var (
+		h    windows.Handle
+		iosb windows.IO_STATUS_BLOCK
+	)
+	err = windows.NtCreateFile(&h, windows.FILE_READ_ATTRIBUTES|windows.SYNCHRONIZE, oa, &iosb, nil, 0,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE, windows.FILE_OPEN,
+		windows.FILE_OPEN_FOR_BACKUP_INTENT|windows.FILE_SYNCHRONOUS_IO_NONALERT, 0, 0)
+	if err != nil {
+		var status windows.NTStatus
+		if errors.As(err, &status) {
+			err = status.Errno()
+		}
+		return nil, &fs.PathError{Op: "NtCreateFile", Path: name, Err: err}
+	}
+	defer windows.CloseHandle(h)
+
+	info := &handleInfo{name: name}
+	if err := windows.GetFileInformationByHandle(h, &info.d); err != nil {
+		return nil, &fs.PathError{Op: "GetFileInformationByHandle", Path: name, Err: err}
+	}
+	return info, nil
+}
+
+// handleInfo is the fs.FileInfo for a windows.ByHandleFileInformation, which
+// Sys returns.
+type handleInfo struct {
+	name string
+	d    windows.ByHandleFileInformation
+}
+
+func (h *handleInfo) Name() string { return h.name }
+func (h *handleInfo) Size() int64  { return int64(h.d.FileSizeHigh)<<32 | int64(h.d.FileSizeLow) }
+func (h *handleInfo) IsDir() bool  { return h.Mode().IsDir() }
+func (h *handleInfo) Sys() any     { return &h.d }
+
+func (h *handleInfo) ModTime() time.Time {
+	return time.Unix(0, h.d.LastWriteTime.Nanoseconds())
+}
+
+// Mode follows the mapping os.Stat uses on Windows.
+func (h *handleInfo) Mode() fs.FileMode {
+	var mode fs.FileMode = 0o666
+	if h.d.FileAttributes&windows.FILE_ATTRIBUTE_READONLY != 0 {
+		mode = 0o444
+	}
+	if h.d.FileAttributes&windows.FILE_ATTRIBUTE_DIRECTORY != 0 {
+		mode |= fs.ModeDir | 0o111
+	}
+	return mode
+}
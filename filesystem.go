@@ -0,0 +1,250 @@
+package ppath
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// FileSystem is the set of filesystem operations that Path routes through the
+// package-level backend. See SetFileSystem for the methods that use it.
+type FileSystem interface {
+	Open(name string) (fs.File, error)
+	OpenFile(name string, flag int, perm fs.FileMode) (File, error)
+	Stat(name string) (fs.FileInfo, error)
+	Lstat(name string) (fs.FileInfo, error)
+	ReadFile(name string) ([]byte, error)
+	ReadDir(name string) ([]fs.DirEntry, error)
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+	MkdirAll(name string, perm fs.FileMode) error
+	RemoveAll(name string) error
+	Rename(oldpath, newpath string) error
+}
+
+// File is an open file as returned by Path.OpenHandle and
+// Path.OpenFileHandle. *os.File implements it; files from a read-only backend
+// fail writes with errors.ErrUnsupported.
+type File interface {
+	fs.File
+	io.Seeker
+	io.ReaderAt
+	io.Writer
+	io.WriterAt
+	Name() string
+	Truncate(size int64) error
+}
+
+var (
+	fsMu       sync.RWMutex
+	fileSystem FileSystem = osFS{}
+)
+
+// SetFileSystem replaces the backend used by the following methods, and only
+// by them:
+//
+//   - OpenHandle and OpenFileHandle;
+//   - Stat, LStat, Info, Size, LSize, AccessError, IsExist, LExists, IsDir,
+//     LIsDir, IsRegular, IsSymlink, IsDev and IsEmpty;
+//   - ReadFile, ReadString, ReadTextFile, ReadDir and ReadDirPage;
+//   - WriteFile, WriteFileMode, MkdirIfNotExist, MkdirIfNotExistMode,
+//     EnsureEmptyDir and Delete;
+//   - Rename, RenameEach, MoveInto and MergeMoveWith;
+//   - Reader, Writer, TeeWriter, AsReader, ReadFileLimit, ReadFileTimeout,
+//     ReadInto, ReadFull, ReadFromPath, WriteAt, WriteTo, WriteToPath,
+//     Allocate, WriteJSON and ReadJSONField;
+//   - Lines, EachLine, Head, Tail and HasBOM;
+//   - Hashes, MD5, SHA1, SHA256, WriteChecksumFile, StoreInto and the
+//     VerifyChecksumFile function;
+//   - Copy, CopyResume, CopySparse, SyncChanged and the file contents copied
+//     by Copier.
+//
+// Everything else, including Open, OpenFile, OpenOrCreate, Create,
+// CreateExclusive, the Walk and List methods, DirHandle, CachedFile, symlink
+// and permission changes and volume queries, always uses the OS filesystem.
+// Passing nil restores the OS filesystem, which is the default. It is meant
+// for tests and affects every Path in the process.
+func SetFileSystem(f FileSystem) {
+	if f == nil {
+		f = osFS{}
+	}
+
+	fsMu.Lock()
+	fileSystem = f
+	fsMu.Unlock()
+}
+
+func filesystem() FileSystem {
+	fsMu.RLock()
+	defer fsMu.RUnlock()
+	return fileSystem
+}
+
+type osFS struct{}
+
+func (osFS) Open(name string) (fs.File, error) {
+	return os.Open(name)
+}
+
+func (osFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	f, err := os.OpenFile(name, flag, perm)
+	if err != nil {
+		// Avoid returning a non-nil interface holding a nil *os.File.
+		return nil, err
+	}
+	return f, nil
+}
+
+func (osFS) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (osFS) Lstat(name string) (fs.FileInfo, error) {
+	return os.Lstat(name)
+}
+
+func (osFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+func (osFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return os.WriteFile(name, data, perm)
+}
+
+func (osFS) MkdirAll(name string, perm fs.FileMode) error {
+	return os.MkdirAll(name, perm)
+}
+
+func (osFS) RemoveAll(name string) error {
+	return os.RemoveAll(name)
+}
+
+func (osFS) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+// FromFS adapts a read-only fs.FS, such as fstest.MapFS, into a FileSystem.
+// Paths are converted to slash-separated names relative to the root of fsys,
+// so "/a/b" and "a/b" refer to the same entry. Write operations fail with
+// errors.ErrUnsupported.
+func FromFS(fsys fs.FS) FileSystem {
+	return readOnlyFS{fsys: fsys}
+}
+
+type readOnlyFS struct {
+	fsys fs.FS
+}
+
+func (r readOnlyFS) name(name string) string {
+	name = strings.TrimLeft(filepath.ToSlash(filepath.Clean(name)), "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+func (r readOnlyFS) Open(name string) (fs.File, error) {
+	return r.fsys.Open(r.name(name))
+}
+
+func (r readOnlyFS) OpenFile(name string, flag int, _ fs.FileMode) (File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_APPEND|os.O_TRUNC) != 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errors.ErrUnsupported}
+	}
+	f, err := r.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return asFile(f, name), nil
+}
+
+func (r readOnlyFS) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(r.fsys, r.name(name))
+}
+
+func (r readOnlyFS) Lstat(name string) (fs.FileInfo, error) {
+	return r.Stat(name)
+}
+
+func (r readOnlyFS) ReadFile(name string) ([]byte, error) {
+	return fs.ReadFile(r.fsys, r.name(name))
+}
+
+func (r readOnlyFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(r.fsys, r.name(name))
+}
+
+func (r readOnlyFS) WriteFile(name string, _ []byte, _ fs.FileMode) error {
+	return &fs.PathError{Op: "write", Path: name, Err: errors.ErrUnsupported}
+}
+
+func (r readOnlyFS) MkdirAll(name string, _ fs.FileMode) error {
+	return &fs.PathError{Op: "mkdir", Path: name, Err: errors.ErrUnsupported}
+}
+
+func (r readOnlyFS) RemoveAll(name string) error {
+	return &fs.PathError{Op: "remove", Path: name, Err: errors.ErrUnsupported}
+}
+
+func (r readOnlyFS) Rename(oldpath, newpath string) error {
+	return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: errors.ErrUnsupported}
+}
+
+// asFile adapts an fs.File to File. Seek and ReadAt are passed through when f
+// supports them; writes fail with errors.ErrUnsupported.
+func asFile(f fs.File, name string) File {
+	if file, ok := f.(File); ok {
+		return file
+	}
+	return readOnlyFile{File: f, name: name}
+}
+
+type readOnlyFile struct {
+	fs.File
+	name string
+}
+
+func (f readOnlyFile) Name() string {
+	return f.name
+}
+
+func (f readOnlyFile) Seek(offset int64, whence int) (int64, error) {
+	if s, ok := f.File.(io.Seeker); ok {
+		return s.Seek(offset, whence)
+	}
+	return 0, &fs.PathError{Op: "seek", Path: f.name, Err: errors.ErrUnsupported}
+}
+
+func (f readOnlyFile) ReadAt(b []byte, off int64) (int, error) {
+	if r, ok := f.File.(io.ReaderAt); ok {
+		return r.ReadAt(b, off)
+	}
+	return 0, &fs.PathError{Op: "read", Path: f.name, Err: errors.ErrUnsupported}
+}
+
+func (f readOnlyFile) Write([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "write", Path: f.name, Err: errors.ErrUnsupported}
+}
+
+func (f readOnlyFile) WriteAt([]byte, int64) (int, error) {
+	return 0, &fs.PathError{Op: "write", Path: f.name, Err: errors.ErrUnsupported}
+}
+
+func (f readOnlyFile) Truncate(int64) error {
+	return &fs.PathError{Op: "truncate", Path: f.name, Err: errors.ErrUnsupported}
+}
+
+// ReadDir lets directories opened through a backend be listed incrementally.
+func (f readOnlyFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if d, ok := f.File.(fs.ReadDirFile); ok {
+		return d.ReadDir(n)
+	}
+	return nil, &fs.PathError{Op: "readdir", Path: f.name, Err: errors.ErrUnsupported}
+}
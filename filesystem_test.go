@@ -0,0 +1,98 @@
+package ppath
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSetFileSystem(t *testing.T) {
+	SetFileSystem(FromFS(fstest.MapFS{
+		"etc/app/config.json": {Data: []byte(`{"debug":true}`)},
+		"etc/app/empty":       {Data: nil},
+		"etc/app/app.log":     {Data: []byte("one\ntwo\nthree\n")},
+		"etc/app/conf.d":      {Mode: fs.ModeDir},
+	}))
+	t.Cleanup(func() { SetFileSystem(nil) })
+
+	dir := New("/etc/app")
+	if !dir.IsDir() {
+		t.Errorf("expected %s to be a directory", dir)
+	}
+	if dir.IsEmpty() {
+		t.Errorf("expected %s to be non-empty", dir)
+	}
+
+	config := dir.Join("config.json")
+	if !config.IsRegular() {
+		t.Errorf("expected %s to be a regular file", config)
+	}
+	content, err := config.ReadFile()
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(content) != `{"debug":true}` {
+		t.Errorf("unexpected content %q", content)
+	}
+
+	entries, err := dir.ReadDir()
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != 4 {
+		t.Errorf("expected 4 entries, got %d", len(entries))
+	}
+	if !dir.Join("conf.d").IsEmpty() {
+		t.Errorf("expected %s to be empty", dir.Join("conf.d"))
+	}
+
+	// Reads through an opened file see the backend as well.
+	f, err := config.OpenHandle()
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	buf := make([]byte, 7)
+	if _, err := f.ReadAt(buf, 1); err != nil || string(buf) != `"debug"` {
+		t.Errorf("expected \"debug\" at offset 1, got %q (%v)", buf, err)
+	}
+	if _, err := f.Write(testContent); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported, got %v", err)
+	}
+	f.Close()
+	if _, err := dir.OpenHandle(); err == nil {
+		t.Errorf("expected error opening a directory")
+	}
+
+	log := dir.Join("app.log")
+	if lines, err := log.Tail(2); err != nil || !slices.Equal(lines, []string{"two", "three"}) {
+		t.Errorf("expected [two three], got %q (%v)", lines, err)
+	}
+	if lines, err := log.Head(1); err != nil || !slices.Equal(lines, []string{"one"}) {
+		t.Errorf("expected [one], got %q (%v)", lines, err)
+	}
+	if _, err := log.ReadFileLimit(4); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("expected ErrTooLarge, got %v", err)
+	}
+	if sum, expected := log.MD5(), md5.Sum([]byte("one\ntwo\nthree\n")); sum != hex.EncodeToString(expected[:]) {
+		t.Errorf("expected %x, got %s", expected, sum)
+	}
+	if err := log.Copy(dir.Join("copy.log")); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported copying into the read-only backend, got %v", err)
+	}
+
+	if dir.Join("missing").Exists() {
+		t.Errorf("expected missing path not to exist")
+	}
+	if err := config.WriteFile(testContent); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported, got %v", err)
+	}
+
+	SetFileSystem(nil)
+	if !New(t.TempDir()).IsDir() {
+		t.Errorf("expected OS filesystem to be restored")
+	}
+}
@@ -0,0 +1,117 @@
+package ppath
+
+import (
+	"path"
+	"strings"
+)
+
+// validPattern reports whether every segment of the slash-separated pattern
+// is well formed.
+func validPattern(pattern string) error {
+	for _, seg := range strings.Split(pattern, "/") {
+		if _, err := path.Match(seg, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchPath reports whether the slash-separated name matches pattern. Each
+// segment is matched with path.Match, except that a "**" segment matches any
+// number of segments, including none. The pattern must be valid.
+func matchPath(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// collapse consecutive "**" segments
+			for len(pattern) > 1 && pattern[1] == "**" {
+				pattern = pattern[1:]
+			}
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// ExpandBraces expands shell-style brace groups in pattern, so
+// "data/{train,test}/images" yields "data/train/images" and
+// "data/test/images". Groups may be nested, and several groups produce every
+// combination, varying the leftmost group slowest. A brace without a matching
+// closing brace, or a group without a comma such as "{a}", is kept literally.
+// The expansion is purely textual: the results are not cleaned and the
+// filesystem is not consulted, so they can be passed on as glob patterns.
+func ExpandBraces(pattern string) []Path {
+	expanded := expandBraces(pattern)
+	paths := make([]Path, len(expanded))
+	for i, s := range expanded {
+		paths[i] = Path(s)
+	}
+	return paths
+}
+
+func expandBraces(s string) []string {
+	for start := 0; ; {
+		i := strings.IndexByte(s[start:], '{')
+		if i < 0 {
+			return []string{s}
+		}
+		i += start
+
+		end, alts := braceGroup(s, i)
+		if end < 0 || len(alts) < 2 {
+			start = i + 1
+			continue
+		}
+
+		prefix, suffixes := s[:i], expandBraces(s[end+1:])
+		var out []string
+		for _, alt := range alts {
+			for _, a := range expandBraces(alt) {
+				for _, suffix := range suffixes {
+					out = append(out, prefix+a+suffix)
+				}
+			}
+		}
+		return out
+	}
+}
+
+// braceGroup finds the brace matching the one at s[open] and splits the
+// group's content at its top-level commas. It returns -1 if there is no
+// matching brace.
+func braceGroup(s string, open int) (end int, alts []string) {
+	depth, last := 0, open+1
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i, append(alts, s[last:i])
+			}
+		case ',':
+			if depth == 1 {
+				alts = append(alts, s[last:i])
+				last = i + 1
+			}
+		}
+	}
+	return -1, nil
+}
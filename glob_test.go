@@ -0,0 +1,57 @@
+package ppath
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		expected      bool
+	}{
+		{"*.proto", "a.proto", true},
+		{"*.proto", "dir/a.proto", false},
+		{"**/*.proto", "a.proto", true},
+		{"**/*.proto", "dir/sub/a.proto", true},
+		{"dir/**", "dir", true},
+		{"dir/**", "dir/sub/a.txt", true},
+		{"dir/**/a.txt", "dir/a.txt", true},
+		{"dir/**/a.txt", "dir/x/y/a.txt", true},
+		{"dir/**/a.txt", "other/a.txt", false},
+		{"**/**/b", "a/b", true},
+		{"a/*/c", "a/b/c", true},
+		{"a/*/c", "a/b/x/c", false},
+		{"**", "anything/at/all", true},
+	}
+
+	for _, tt := range tests {
+		if got := matchPath(tt.pattern, tt.name); got != tt.expected {
+			t.Errorf("matchPath(%q, %q): expected %v, got %v", tt.pattern, tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestExpandBraces(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		expected []Path
+	}{
+		{"data/{train,test}/images", []Path{"data/train/images", "data/test/images"}},
+		{"{a,b}{1,2}", []Path{"a1", "a2", "b1", "b2"}},
+		{"x{a,b{c,d}}y", []Path{"xay", "xbcy", "xbdy"}},
+		{"{a,}.txt", []Path{"a.txt", ".txt"}},
+		{"plain/path", []Path{"plain/path"}},
+		{"{single}", []Path{"{single}"}},
+		{"open{a,b", []Path{"open{a,b"}},
+		{"{open{a,b}", []Path{"{opena", "{openb"}},
+		{"{a,b}/**/*.txt", []Path{"a/**/*.txt", "b/**/*.txt"}},
+		{"", []Path{""}},
+	}
+
+	for _, tt := range tests {
+		if got := ExpandBraces(tt.pattern); !slices.Equal(got, tt.expected) {
+			t.Errorf("ExpandBraces(%q): expected %v, got %v", tt.pattern, tt.expected, got)
+		}
+	}
+}
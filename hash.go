@@ -0,0 +1,410 @@
+package ppath
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/maa3x/errz"
+)
+
+var hashFuncs = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha224": sha256.New224,
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+func hashFunc(algo string) (func() hash.Hash, error) {
+	h, ok := hashFuncs[strings.ToLower(algo)]
+	if !ok {
+		return nil, errz.E("unknown hash algorithm").With("algo", algo)
+	}
+	return h, nil
+}
+
+// Hashes reads the file once and returns its hex digest for each of the
+// requested algorithms, keyed by algorithm name. Supported algorithms are
+// md5, sha1, sha224, sha256, sha384 and sha512.
+func (p Path) Hashes(algos ...string) (map[string]string, error) {
+	if len(algos) == 0 {
+		return nil, errz.E("no hash algorithm given")
+	}
+
+	hashes := make(map[string]hash.Hash, len(algos))
+	writers := make([]io.Writer, 0, len(algos))
+	for _, algo := range algos {
+		if _, ok := hashes[algo]; ok {
+			continue
+		}
+		newHash, err := hashFunc(algo)
+		if err != nil {
+			return nil, err
+		}
+		h := newHash()
+		hashes[algo] = h
+		writers = append(writers, h)
+	}
+
+	f, err := p.OpenHandle()
+	if err != nil {
+		return nil, errz.E(err, "open file")
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(io.MultiWriter(writers...), f); err != nil {
+		return nil, errz.E(err, "read file")
+	}
+
+	sums := make(map[string]string, len(hashes))
+	for algo, h := range hashes {
+		sums[algo] = hex.EncodeToString(h.Sum(nil))
+	}
+	return sums, nil
+}
+
+// HashTree walks p and hashes every regular file with a pool of workers,
+// returning the hex digest of each file keyed by its path. Symlinks are not
+// followed. Files that cannot be read are left out of the result and reported
+// in the returned error, which joins one error per failing path.
+func (p Path) HashTree(h func() hash.Hash, workers int) (map[Path]string, error) {
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		sums = make(map[Path]string)
+		errs []error
+	)
+	addErr := func(err error, msg string, path Path) {
+		mu.Lock()
+		errs = append(errs, errz.E(err, msg).With("path", path))
+		mu.Unlock()
+	}
+
+	files := make(chan Path)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range files {
+				sum, err := f.digest(h())
+				if err != nil {
+					addErr(err, "hash file", f)
+					continue
+				}
+				mu.Lock()
+				sums[f] = sum
+				mu.Unlock()
+			}
+		}()
+	}
+
+	walkErr := p.Walk(func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d == nil && path == string(p) {
+				return err
+			}
+			addErr(err, "walk", Path(path))
+			return nil
+		}
+		if d.Type().IsRegular() {
+			files <- Path(path)
+		}
+		return nil
+	})
+	close(files)
+	wg.Wait()
+
+	if walkErr != nil {
+		return nil, errz.E(walkErr, "walk directory")
+	}
+	return sums, errz.Join(errs...)
+}
+
+// WriteChecksumFile writes the digest of p to a sibling file named
+// "<name>.<algo>" in the "<hex>  <name>" format understood by tools such as
+// sha256sum -c, and returns the path of the written file.
+func (p Path) WriteChecksumFile(algo string) (Path, error) {
+	algo = strings.ToLower(algo)
+	newHash, err := hashFunc(algo)
+	if err != nil {
+		return "", err
+	}
+
+	sum, err := p.digest(newHash())
+	if err != nil {
+		return "", errz.E(err, "hash file")
+	}
+
+	out := Path(string(p) + "." + algo)
+	line := sum + "  " + string(p.Base()) + "\n"
+	if err := out.WriteFile([]byte(line)); err != nil {
+		return "", errz.E(err, "write checksum file")
+	}
+	return out, nil
+}
+
+// VerifyChecksumFile checks every entry of a checksum file in the
+// "<hex>  <name>" (text) or "<hex> *<name>" (binary) format. File names are
+// resolved relative to the directory of the checksum file. The algorithm is
+// taken from the checksum file extension, or inferred from the digest length
+// when the extension is not a known algorithm. Every failing entry is
+// reported in the returned error.
+func VerifyChecksumFile(checksumFile Path) error {
+	f, err := checksumFile.OpenHandle()
+	if err != nil {
+		return errz.E(err, "open checksum file")
+	}
+	defer f.Close()
+
+	dir := checksumFile.Dir()
+	algo := strings.TrimPrefix(string(checksumFile.Ext()), ".")
+
+	var errs []error
+	entries := 0
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		sum, name, ok := strings.Cut(line, " ")
+		if !ok || name == "" || (name[0] != ' ' && name[0] != '*') {
+			errs = append(errs, errz.E("malformed checksum line").With("line", lineNum))
+			continue
+		}
+		name = name[1:]
+		entries++
+
+		newHash, err := checksumHashFunc(algo, sum)
+		if err != nil {
+			errs = append(errs, errz.E(err).With("line", lineNum))
+			continue
+		}
+
+		target := New(name)
+		if !target.IsAbs() {
+			target = dir.Join(filepath.FromSlash(name))
+		}
+		actual, err := target.digest(newHash())
+		if err != nil {
+			errs = append(errs, errz.E(err, "hash file").With("name", name))
+			continue
+		}
+		if !strings.EqualFold(actual, sum) {
+			errs = append(errs, errz.E("checksum mismatch").With("name", name))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return errz.E(err, "read checksum file")
+	}
+	if entries == 0 && len(errs) == 0 {
+		return errz.E("no checksum entries found")
+	}
+
+	return errz.Join(errs...)
+}
+
+func checksumHashFunc(algo, sum string) (func() hash.Hash, error) {
+	if h, ok := hashFuncs[strings.ToLower(algo)]; ok {
+		return h, nil
+	}
+
+	switch len(sum) {
+	case md5.Size * 2:
+		return md5.New, nil
+	case sha1.Size * 2:
+		return sha1.New, nil
+	case sha256.Size224 * 2:
+		return sha256.New224, nil
+	case sha256.Size * 2:
+		return sha256.New, nil
+	case sha512.Size384 * 2:
+		return sha512.New384, nil
+	case sha512.Size * 2:
+		return sha512.New, nil
+	}
+	return nil, errz.E("unable to determine hash algorithm")
+}
+
+// StoreInto copies p into the content-addressed store rooted at dir, under
+// dir/<first two hex digits>/<remaining hex digits> of its algo digest, and
+// returns the stored path. Nothing is copied if the blob is already present.
+// The blob is written under a temporary name and renamed into place, so a
+// partially written blob is never visible under its final name.
+func (p Path) StoreInto(dir Path, algo string) (Path, error) {
+	newHash, err := hashFunc(algo)
+	if err != nil {
+		return "", err
+	}
+	sum, err := p.digest(newHash())
+	if err != nil {
+		return "", errz.E(err, "hash file")
+	}
+
+	target := blobPath(dir, sum)
+	if target.Exists() {
+		return target, nil
+	}
+	if err := target.Dir().MkdirIfNotExist(); err != nil {
+		return "", errz.E(err, "create shard directory")
+	}
+
+	tmp, err := os.CreateTemp(target.Dir().String(), ".tmp-*")
+	if err != nil {
+		return "", errz.E(err, "create temporary file")
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	if err := p.WriteToPath(Path(tmp.Name())); err != nil {
+		return "", errz.E(err, "copy file")
+	}
+	if err := os.Rename(tmp.Name(), target.String()); err != nil {
+		return "", errz.E(err, "rename file")
+	}
+	return target, nil
+}
+
+// FetchByHash returns the path of the blob with the given hex digest in the
+// content-addressed store rooted at dir, and whether it exists.
+func FetchByHash(dir Path, hash string) (Path, bool) {
+	hash = strings.ToLower(hash)
+	if len(hash) < 3 {
+		return "", false
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return "", false
+	}
+
+	target := blobPath(dir, hash)
+	return target, target.IsRegular()
+}
+
+func blobPath(dir Path, sum string) Path {
+	return dir.Join(sum[:2], sum[2:])
+}
+
+// TreeHash returns a SHA-256 hex digest identifying the structure and content
+// of the tree rooted at p, for detecting whether it changed. Entries below p
+// are visited in the order of WalkSorted, and for each one a record of
+// NUL-terminated fields is hashed:
+//
+//   - directories: "d", the path
+//   - regular files: "f", the path, "x" if the owner-executable bit is set or
+//     "-" otherwise, and the SHA-256 hex digest of the content
+//   - symlinks: "l", the path, and the link target, which is not followed
+//
+// Paths are relative to p and slash-separated, and link targets are converted
+// to slashes as well. Other file types are skipped, and timestamps, owners and
+// the remaining permission bits are not included, so the digest is the same on
+// every platform for the same tree. Windows reports no executable bit, so
+// trees containing executable files hash differently there.
+func (p Path) TreeHash() (string, error) {
+	return p.TreeHashExcluding()
+}
+
+// TreeHashExcluding is like TreeHash but leaves out entries matching any of
+// patterns, such as ".git" or "*.swp". A pattern without a slash is matched
+// against the entry's name, so it applies at any depth; other patterns are
+// matched against the slash-separated path relative to p, where a "**"
+// segment matches any number of directories. An excluded directory is pruned
+// with everything below it, so its contents are not even read.
+func (p Path) TreeHashExcluding(patterns ...string) (string, error) {
+	for _, pattern := range patterns {
+		if err := validPattern(pattern); err != nil {
+			return "", errz.E(err, "invalid exclude pattern").With("pattern", pattern)
+		}
+	}
+	excluded := func(rel string) bool {
+		for _, pattern := range patterns {
+			name := rel
+			if !strings.Contains(pattern, "/") {
+				name = rel[strings.LastIndexByte(rel, '/')+1:]
+			}
+			if matchPath(pattern, name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	h := sha256.New()
+	write := func(fields ...string) {
+		for _, f := range fields {
+			io.WriteString(h, f)
+			h.Write([]byte{0})
+		}
+	}
+
+	err := p.WalkSorted(func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == string(p) {
+			if !d.IsDir() {
+				return errz.E("not a directory").With("path", p)
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(string(p), path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if excluded(rel) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		switch {
+		case d.IsDir():
+			write("d", rel)
+		case d.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return errz.E(err, "read symlink").With("path", path)
+			}
+			write("l", rel, filepath.ToSlash(target))
+		case d.Type().IsRegular():
+			fi, err := d.Info()
+			if err != nil {
+				return errz.E(err, "stat").With("path", path)
+			}
+			exec := "-"
+			if fi.Mode().Perm()&0o100 != 0 {
+				exec = "x"
+			}
+			sum, err := Path(path).digest(sha256.New())
+			if err != nil {
+				return errz.E(err, "hash file").With("path", path)
+			}
+			write("f", rel, exec, sum)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
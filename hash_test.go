@@ -0,0 +1,324 @@
+package ppath
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHashTree(t *testing.T) {
+	root := New(t.TempDir())
+	files := map[Path][]byte{
+		root.Join("a.txt"):             []byte("a"),
+		root.Join("sub", "b.txt"):      []byte("b"),
+		root.Join("sub", "c", "c.txt"): []byte("c"),
+	}
+	for f, content := range files {
+		if err := f.WriteFile(content); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	if err := os.Symlink(root.Join("a.txt").String(), root.Join("link").String()); err != nil {
+		t.Fatalf("os.Symlink: %v", err)
+	}
+
+	sums, err := root.HashTree(sha256.New, 2)
+	if err != nil {
+		t.Fatalf("HashTree: %v", err)
+	}
+	if len(sums) != len(files) {
+		t.Errorf("expected %d digests, got %d", len(files), len(sums))
+	}
+	for f, content := range files {
+		h := sha256.Sum256(content)
+		if expected := hex.EncodeToString(h[:]); sums[f] != expected {
+			t.Errorf("expected %s for %s, got %s", expected, f, sums[f])
+		}
+	}
+
+	if _, err := root.Join("missing").HashTree(sha256.New, 0); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
+func TestHashes(t *testing.T) {
+	p := New(t.TempDir()).Join("file.txt")
+	if err := p.WriteFile(testContent); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	sums, err := p.Hashes("md5", "sha1", "sha256", "sha512")
+	if err != nil {
+		t.Fatalf("Hashes: %v", err)
+	}
+	md5Sum := md5.Sum(testContent)
+	sha1Sum := sha1.Sum(testContent)
+	sha256Sum := sha256.Sum256(testContent)
+	sha512Sum := sha512.Sum512(testContent)
+	expected := map[string]string{
+		"md5":    hex.EncodeToString(md5Sum[:]),
+		"sha1":   hex.EncodeToString(sha1Sum[:]),
+		"sha256": hex.EncodeToString(sha256Sum[:]),
+		"sha512": hex.EncodeToString(sha512Sum[:]),
+	}
+	for algo, sum := range expected {
+		if sums[algo] != sum {
+			t.Errorf("expected %s for %s, got %s", sum, algo, sums[algo])
+		}
+	}
+
+	if _, err := p.Hashes("md5", "crc1"); err == nil {
+		t.Errorf("expected error for unknown algorithm, got nil")
+	}
+	if _, err := p.Hashes(); err == nil {
+		t.Errorf("expected error for no algorithms, got nil")
+	}
+}
+
+func TestWriteChecksumFile(t *testing.T) {
+	p := New(t.TempDir()).Join("artifact.bin")
+	if err := p.WriteFile(testContent); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	out, err := p.WriteChecksumFile("sha256")
+	if err != nil {
+		t.Fatalf("WriteChecksumFile: %v", err)
+	}
+	if out != Path(p.String()+".sha256") {
+		t.Errorf("unexpected checksum file path %s", out)
+	}
+
+	content, err := out.ReadFile()
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	sum := sha256.Sum256(testContent)
+	expected := hex.EncodeToString(sum[:]) + "  artifact.bin\n"
+	if string(content) != expected {
+		t.Errorf("expected %q, got %q", expected, content)
+	}
+
+	if _, err := p.WriteChecksumFile("crc1"); err == nil {
+		t.Errorf("expected error for unknown algorithm, got nil")
+	}
+}
+
+func TestVerifyChecksumFile(t *testing.T) {
+	dir := New(t.TempDir())
+	a := dir.Join("a.txt")
+	b := dir.Join("sub", "b.txt")
+	if err := a.WriteFile([]byte("a")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := b.WriteFile([]byte("b")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	sumA := sha256.Sum256([]byte("a"))
+	sumB := sha256.Sum256([]byte("b"))
+
+	t.Run("Valid", func(t *testing.T) {
+		sums := dir.Join("SHA256SUMS")
+		content := hex.EncodeToString(sumA[:]) + "  a.txt\n" + hex.EncodeToString(sumB[:]) + " *sub/b.txt\n"
+		if err := sums.WriteFile([]byte(content)); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		if err := VerifyChecksumFile(sums); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		out, err := a.WriteChecksumFile("md5")
+		if err != nil {
+			t.Fatalf("WriteChecksumFile: %v", err)
+		}
+		if err := VerifyChecksumFile(out); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Mismatch", func(t *testing.T) {
+		sums := dir.Join("bad.sha256")
+		content := hex.EncodeToString(sumA[:]) + "  a.txt\n" + hex.EncodeToString(sumA[:]) + "  sub/b.txt\n"
+		if err := sums.WriteFile([]byte(content)); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		if err := VerifyChecksumFile(sums); err == nil {
+			t.Errorf("expected error, got nil")
+		}
+	})
+}
+
+func TestStoreInto(t *testing.T) {
+	tempDir := New(t.TempDir())
+	store := tempDir.Join("store")
+	src := tempDir.Join("blob.txt")
+	if err := src.WriteFile(testContent); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	stored, err := src.StoreInto(store, "sha256")
+	if err != nil {
+		t.Fatalf("StoreInto: %v", err)
+	}
+	sum := sha256.Sum256(testContent)
+	digest := hex.EncodeToString(sum[:])
+	if expected := store.Join(digest[:2], digest[2:]); stored != expected {
+		t.Errorf("expected %s, got %s", expected, stored)
+	}
+	content, err := stored.ReadFile()
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(content) != string(testContent) {
+		t.Errorf("expected %s, got %s", testContent, content)
+	}
+
+	again, err := src.StoreInto(store, "sha256")
+	if err != nil || again != stored {
+		t.Errorf("expected %s, got %s, error: %v", stored, again, err)
+	}
+	entries, err := stored.Dir().ReadDir()
+	if err != nil || len(entries) != 1 {
+		t.Errorf("expected a single entry in the shard directory, got %d, error: %v", len(entries), err)
+	}
+
+	fetched, ok := FetchByHash(store, strings.ToUpper(digest))
+	if !ok || fetched != stored {
+		t.Errorf("expected (%s, true), got (%s, %v)", stored, fetched, ok)
+	}
+	if _, ok := FetchByHash(store, "00"+digest[2:]); ok {
+		t.Errorf("expected missing blob not to be found")
+	}
+	if _, ok := FetchByHash(store, "../etc"); ok {
+		t.Errorf("expected invalid hash not to be found")
+	}
+}
+
+func TestTreeHash(t *testing.T) {
+	build := func(t *testing.T) Path {
+		root := New(t.TempDir())
+		for name, content := range map[string]string{"a.txt": "a", "sub/b.txt": "b"} {
+			if err := root.Join(name).WriteFile([]byte(content)); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+		}
+		if err := os.Symlink("a.txt", root.Join("link").String()); err != nil {
+			t.Fatalf("os.Symlink: %v", err)
+		}
+		return root
+	}
+	treeHash := func(t *testing.T, p Path) string {
+		sum, err := p.TreeHash()
+		if err != nil {
+			t.Fatalf("TreeHash: %v", err)
+		}
+		return sum
+	}
+
+	base := treeHash(t, build(t))
+	if other := treeHash(t, build(t)); other != base {
+		t.Errorf("expected identical trees to hash equally, got %s and %s", base, other)
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(root Path) error
+		changed bool
+	}{
+		{"Content", func(root Path) error { return root.Join("a.txt").WriteFile([]byte("changed")) }, true},
+		{"Rename", func(root Path) error { return root.Join("sub", "b.txt").Move(root.Join("sub", "c.txt")) }, true},
+		{"EmptyDir", func(root Path) error { return root.Join("empty").MkdirIfNotExist() }, true},
+		{"SymlinkTarget", func(root Path) error {
+			if err := root.Join("link").Delete(); err != nil {
+				return err
+			}
+			return os.Symlink("sub/b.txt", root.Join("link").String())
+		}, true},
+		{"Executable", func(root Path) error { return os.Chmod(root.Join("a.txt").String(), 0o755) }, runtime.GOOS != "windows"},
+		{"ModTime", func(root Path) error {
+			mtime := time.Now().Add(-time.Hour)
+			return os.Chtimes(root.Join("a.txt").String(), mtime, mtime)
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := build(t)
+			if err := tt.modify(root); err != nil {
+				t.Fatalf("modify: %v", err)
+			}
+			if changed := treeHash(t, root) != base; changed != tt.changed {
+				t.Errorf("expected changed to be %v, got %v", tt.changed, changed)
+			}
+		})
+	}
+
+	file := New(t.TempDir(), "file.txt")
+	if err := file.WriteFile(nil); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if _, err := file.TreeHash(); err == nil {
+		t.Errorf("expected error for a file, got nil")
+	}
+}
+
+func TestTreeHashExcluding(t *testing.T) {
+	root := New(t.TempDir())
+	for _, name := range []string{"main.go", "pkg/util.go"} {
+		if err := root.Join(name).WriteFile([]byte(name)); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	patterns := []string{".git", "*.log", "pkg/**/*.swp", "build"}
+	base, err := root.TreeHashExcluding(patterns...)
+	if err != nil {
+		t.Fatalf("TreeHashExcluding: %v", err)
+	}
+	if plain, _ := root.TreeHash(); plain != base {
+		t.Errorf("expected %s without excluded entries, got %s", plain, base)
+	}
+
+	for _, name := range []string{".git/HEAD", "pkg/.git/config", "run.log", "pkg/debug.log", "pkg/x.swp", "build/out"} {
+		if err := root.Join(name).WriteFile([]byte("volatile")); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	if sum, err := root.TreeHashExcluding(patterns...); err != nil || sum != base {
+		t.Errorf("expected excluded entries to be ignored, got %s, error: %v", sum, err)
+	}
+
+	if err := root.Join("notes.swp").WriteFile(nil); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if sum, _ := root.TreeHashExcluding(patterns...); sum == base {
+		t.Errorf("expected notes.swp outside pkg to change the hash")
+	}
+
+	// Excluded directories are pruned without being read.
+	if runtime.GOOS != "windows" && os.Geteuid() != 0 {
+		locked := root.Join(".git", "objects")
+		if err := locked.MkdirIfNotExist(); err != nil {
+			t.Fatalf("MkdirIfNotExist: %v", err)
+		}
+		if err := os.Chmod(locked.String(), 0); err != nil {
+			t.Fatalf("Chmod: %v", err)
+		}
+		t.Cleanup(func() { os.Chmod(locked.String(), 0o755) })
+		if _, err := root.TreeHashExcluding(".git"); err != nil {
+			t.Errorf("expected pruned directory not to be read, got %v", err)
+		}
+	}
+
+	if _, err := root.TreeHashExcluding("["); err == nil {
+		t.Errorf("expected error for an invalid pattern, got nil")
+	}
+}
@@ -0,0 +1,36 @@
+//go:build linux || darwin
+
+package ppath
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+
+	"github.com/maa3x/errz"
+	"golang.org/x/sys/unix"
+)
+
+func fileID(path string) (FileID, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return FileID{}, err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return FileID{}, errz.E("file id not available")
+	}
+	return FileID{Device: uint64(stat.Dev), Index: stat.Ino}, nil //nolint:unconvert // Dev is int32 on darwin
+}
+
+// infoID returns the FileID recorded in fi, if the platform provides one. It
+// understands both os.Stat results and those of DirHandle.Stat.
+func infoID(fi fs.FileInfo) (FileID, bool) {
+	switch stat := fi.Sys().(type) {
+	case *syscall.Stat_t:
+		return FileID{Device: uint64(stat.Dev), Index: stat.Ino}, true //nolint:unconvert // Dev is int32 on darwin
+	case *unix.Stat_t:
+		return FileID{Device: uint64(stat.Dev), Index: stat.Ino}, true //nolint:unconvert // Dev is int32 on darwin
+	}
+	return FileID{}, false
+}
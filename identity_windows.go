@@ -0,0 +1,40 @@
+//go:build windows
+
+package ppath
+
+import (
+	"io/fs"
+
+	"golang.org/x/sys/windows"
+)
+
+func fileID(path string) (FileID, error) {
+	handle, err := openHandle(path)
+	if err != nil {
+		return FileID{}, err
+	}
+	defer windows.CloseHandle(handle)
+
+	var info windows.ByHandleFileInformation
+	if err := windows.GetFileInformationByHandle(handle, &info); err != nil {
+		return FileID{}, err
+	}
+	return FileID{
+		Device: uint64(info.VolumeSerialNumber),
+		Index:  uint64(info.FileIndexHigh)<<32 | uint64(info.FileIndexLow),
+	}, nil
+}
+
+// infoID returns the FileID recorded in fi. The file information returned by
+// os.Stat on Windows does not carry the file index, so it is only available
+// for results of DirHandle.Stat.
+func infoID(fi fs.FileInfo) (FileID, bool) {
+	d, ok := fi.Sys().(*windows.ByHandleFileInformation)
+	if !ok {
+		return FileID{}, false
+	}
+	return FileID{
+		Device: uint64(d.VolumeSerialNumber),
+		Index:  uint64(d.FileIndexHigh)<<32 | uint64(d.FileIndexLow),
+	}, true
+}
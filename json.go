@@ -0,0 +1,108 @@
+package ppath
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/maa3x/errz"
+)
+
+// ErrFieldNotFound is returned by ReadJSONField when the document has no
+// value at the requested field path.
+var ErrFieldNotFound = errors.New("JSON field not found")
+
+// ReadJSONField returns the raw JSON value at the dotted field path in the
+// file, such as "metadata.version" or "items.0.name", where a numeric
+// segment indexes an array. The document is streamed with a json.Decoder:
+// values before the field are skipped token by token without being
+// unmarshaled, and reading stops as soon as the field is found. An empty path
+// returns the whole document. Keys containing dots cannot be addressed, and
+// the first of duplicate keys wins. A missing field fails with an error
+// matching ErrFieldNotFound; malformed JSON fails with a different error.
+func (p Path) ReadJSONField(path string) (json.RawMessage, error) {
+	f, err := p.OpenHandle()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var segs []string
+	if path != "" {
+		segs = strings.Split(path, ".")
+	}
+	dec := json.NewDecoder(f)
+	raw, found, err := findJSONField(dec, segs)
+	if err != nil {
+		return nil, errz.E(err, "parse JSON").With("path", p)
+	}
+	if !found {
+		return nil, errz.E(ErrFieldNotFound).With("path", p).With("field", path)
+	}
+	return raw, nil
+}
+
+func findJSONField(dec *json.Decoder, segs []string) (json.RawMessage, bool, error) {
+	if len(segs) == 0 {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, false, err
+		}
+		return raw, true, nil
+	}
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, false, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, false, err
+			}
+			if key == segs[0] {
+				return findJSONField(dec, segs[1:])
+			}
+			if err := skipJSONValue(dec); err != nil {
+				return nil, false, err
+			}
+		}
+	case json.Delim('['):
+		index, err := strconv.Atoi(segs[0])
+		if err != nil || index < 0 {
+			return nil, false, nil
+		}
+		for i := 0; dec.More(); i++ {
+			if i == index {
+				return findJSONField(dec, segs[1:])
+			}
+			if err := skipJSONValue(dec); err != nil {
+				return nil, false, err
+			}
+		}
+	}
+	return nil, false, nil
+}
+
+// skipJSONValue reads past the next value, however deeply nested.
+func skipJSONValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package ppath
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReadJSONField(t *testing.T) {
+	p := New(t.TempDir()).Join("package-lock.json")
+	doc := `{
+		"name": "app",
+		"packages": {"": {"deps": [1, 2, {"x": [3]}]}, "node_modules/a": {"version": "1.0.0"}},
+		"metadata": {"version": "3.2.1", "tags": ["a", "b"]},
+		"items": [{"name": "first"}, {"name": "second", "sizes": [10, 20]}],
+		"empty": null
+	}`
+	if err := p.WriteFile([]byte(doc)); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	tests := []struct {
+		field    string
+		expected string
+	}{
+		{"name", `"app"`},
+		{"metadata.version", `"3.2.1"`},
+		{"metadata.tags.1", `"b"`},
+		{"items.1.name", `"second"`},
+		{"items.1.sizes", `[10, 20]`},
+		{"packages.node_modules/a.version", `"1.0.0"`},
+		{"empty", `null`},
+	}
+	for _, tt := range tests {
+		raw, err := p.ReadJSONField(tt.field)
+		if err != nil {
+			t.Errorf("ReadJSONField(%q): %v", tt.field, err)
+			continue
+		}
+		if string(raw) != tt.expected {
+			t.Errorf("ReadJSONField(%q): expected %s, got %s", tt.field, tt.expected, raw)
+		}
+	}
+
+	if raw, err := p.ReadJSONField(""); err != nil || len(raw) == 0 {
+		t.Errorf("expected the whole document, got %d bytes, error: %v", len(raw), err)
+	}
+
+	for _, field := range []string{"missing", "metadata.missing", "items.5.name", "items.x", "name.inner", "items.-1"} {
+		if _, err := p.ReadJSONField(field); !errors.Is(err, ErrFieldNotFound) {
+			t.Errorf("ReadJSONField(%q): expected ErrFieldNotFound, got %v", field, err)
+		}
+	}
+
+	bad := p.Dir().Join("bad.json")
+	if err := bad.WriteFile([]byte(`{"a": [1, 2,, 3], "b": 1}`)); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if _, err := bad.ReadJSONField("b"); err == nil || errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("expected a parse error, got %v", err)
+	}
+}
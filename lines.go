@@ -0,0 +1,137 @@
+package ppath
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"iter"
+	"slices"
+	"strings"
+)
+
+// Lines opens the file and yields its lines one at a time without the line
+// terminator ("\n" or "\r\n"). The file is closed when the iteration finishes
+// or the consumer stops early. A failure to open or read the file is yielded
+// once as the error value, after which the iteration ends.
+func (p Path) Lines() iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		f, err := p.OpenHandle()
+		if err != nil {
+			yield("", err)
+			return
+		}
+		defer f.Close()
+
+		r := bufio.NewReader(f)
+		for {
+			line, err := r.ReadString('\n')
+			if line != "" && (err == nil || errors.Is(err, io.EOF)) {
+				line = strings.TrimSuffix(line, "\n")
+				if !yield(strings.TrimSuffix(line, "\r"), nil) {
+					return
+				}
+			}
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					yield("", err)
+				}
+				return
+			}
+		}
+	}
+}
+
+// EachLine calls fn for each line of the file with its 1-based line number,
+// reading the file as Lines does. It stops at the first error fn returns and
+// returns that error unchanged.
+func (p Path) EachLine(fn func(lineNum int, line string) error) error {
+	n := 0
+	for line, err := range p.Lines() {
+		if err != nil {
+			return err
+		}
+		n++
+		if err := fn(n, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Head returns the first n lines of the file without their line terminators.
+// Reading stops as soon as n lines have been seen.
+func (p Path) Head(n int) ([]string, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+
+	lines := make([]string, 0, min(n, 64))
+	for line, err := range p.Lines() {
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+		if len(lines) == n {
+			break
+		}
+	}
+	return lines, nil
+}
+
+// tailBlockSize is the size of the blocks Tail reads backward from the end.
+const tailBlockSize = 64 << 10
+
+// Tail returns the last n lines of the file without their line terminators.
+// The file is read backward from the end in blocks, so only the tail of a large
+// file is loaded. A final line without a trailing newline counts as a line.
+func (p Path) Tail(n int) ([]string, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+
+	f, err := p.OpenHandle()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	// Blocks are collected last to first and joined once, so each byte is read
+	// and scanned for newlines a single time.
+	var blocks [][]byte
+	newlines := 0
+	for offset > 0 {
+		size := min(offset, tailBlockSize)
+		offset -= size
+		block := make([]byte, size)
+		if _, err := f.ReadAt(block, offset); err != nil {
+			return nil, err
+		}
+		if len(blocks) == 0 {
+			// The file's trailing newline does not start another line.
+			block = bytes.TrimSuffix(block, []byte("\n"))
+		}
+		blocks = append(blocks, block)
+		newlines += bytes.Count(block, []byte("\n"))
+		if newlines >= n {
+			break
+		}
+	}
+
+	if len(blocks) == 0 {
+		return nil, nil
+	}
+	slices.Reverse(blocks)
+	data := bytes.Join(blocks, nil)
+	all := strings.Split(string(data), "\n")
+	lines := all[max(len(all)-n, 0):]
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines, nil
+}
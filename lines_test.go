@@ -0,0 +1,147 @@
+package ppath
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	p := New(t.TempDir()).Join("file.txt")
+	long := strings.Repeat("x", 200_000)
+	if err := p.WriteFile([]byte("one\r\ntwo\n\n" + long + "\nlast")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	var lines []string
+	for line, err := range p.Lines() {
+		if err != nil {
+			t.Fatalf("Lines: %v", err)
+		}
+		lines = append(lines, line)
+	}
+	expected := []string{"one", "two", "", long, "last"}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d lines, got %d", len(expected), len(lines))
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("line %d: expected %.20q, got %.20q", i, expected[i], lines[i])
+		}
+	}
+
+	count := 0
+	for range p.Lines() {
+		count++
+		if count == 2 {
+			break
+		}
+	}
+	if count != 2 {
+		t.Errorf("expected early exit after 2 lines, got %d", count)
+	}
+
+	var gotErr error
+	for _, err := range p.Dir().Join("missing").Lines() {
+		gotErr = err
+	}
+	if gotErr == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
+func TestEachLine(t *testing.T) {
+	p := New(t.TempDir()).Join("file.txt")
+	long := strings.Repeat("x", 200_000)
+	if err := p.WriteFile([]byte("one\r\n\n" + long + "\nlast")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	var got []string
+	err := p.EachLine(func(lineNum int, line string) error {
+		got = append(got, fmt.Sprintf("%d:%.5s", lineNum, line))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("EachLine: %v", err)
+	}
+	expected := []string{"1:one", "2:", "3:xxxxx", "4:last"}
+	if !slices.Equal(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = p.EachLine(func(lineNum int, line string) error {
+		calls++
+		if lineNum == 2 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || calls != 2 {
+		t.Errorf("expected stop after 2 calls, got %d calls, error: %v", calls, err)
+	}
+
+	if err := p.Dir().Join("missing").EachLine(func(int, string) error { return nil }); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
+func TestHeadTail(t *testing.T) {
+	dir := New(t.TempDir())
+	var many []string
+	for i := range 20_000 {
+		many = append(many, fmt.Sprintf("line %d", i))
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		n       int
+		head    []string
+		tail    []string
+	}{
+		{"trailing newline", "a\nb\nc\n", 2, []string{"a", "b"}, []string{"b", "c"}},
+		{"no trailing newline", "a\nb\nc", 2, []string{"a", "b"}, []string{"b", "c"}},
+		{"crlf", "a\r\nb\r\n", 1, []string{"a"}, []string{"b"}},
+		{"shorter than n", "a\nb", 5, []string{"a", "b"}, []string{"a", "b"}},
+		{"blank lines", "a\n\n\n", 2, []string{"a", ""}, []string{"", ""}},
+		{"single newline", "\n", 3, []string{""}, []string{""}},
+		{"empty", "", 3, nil, nil},
+		{"zero", "a\nb\n", 0, nil, nil},
+		{"large", strings.Join(many, "\n") + "\n", 3, many[:3], many[len(many)-3:]},
+		{"across blocks", strings.Join(many, "\n"), 15_000, many[:15_000], many[5_000:]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := dir.Join(strings.ReplaceAll(tt.name, " ", "_"))
+			if err := p.WriteFile([]byte(tt.content)); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+
+			head, err := p.Head(tt.n)
+			if err != nil {
+				t.Fatalf("Head: %v", err)
+			}
+			if !slices.Equal(head, tt.head) {
+				t.Errorf("Head: expected %q, got %q", tt.head, head)
+			}
+
+			tail, err := p.Tail(tt.n)
+			if err != nil {
+				t.Fatalf("Tail: %v", err)
+			}
+			if !slices.Equal(tail, tt.tail) {
+				t.Errorf("Tail: expected %q, got %q", tt.tail, tail)
+			}
+		})
+	}
+
+	if _, err := dir.Join("missing").Tail(1); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
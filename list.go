@@ -0,0 +1,277 @@
+package ppath
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/maa3x/errz"
+)
+
+// ListType selects which kinds of entries List returns.
+type ListType int
+
+const (
+	// ListAll returns files, directories and any other entries.
+	ListAll ListType = iota
+	// ListFiles returns regular files only.
+	ListFiles
+	// ListDirs returns directories only.
+	ListDirs
+)
+
+// ListOptions configures List. The zero value lists every immediate child.
+type ListOptions struct {
+	// Recursive descends into subdirectories.
+	Recursive bool
+	// MaxDepth limits how deep a recursive listing goes, counting immediate
+	// children as depth 1. Zero means no limit.
+	MaxDepth int
+	// Type selects which kinds of entries are returned.
+	Type ListType
+	// Pattern, if set, is matched against the slash-separated path relative
+	// to the listed directory as in WalkMatch, so "**" spans directories.
+	Pattern string
+	// SkipHidden leaves out entries whose name starts with a dot, and does
+	// not descend into such directories.
+	SkipHidden bool
+}
+
+// ListOption configures SubdirNames and FileNames.
+type ListOption func(*ListOptions)
+
+// SkipHidden leaves out entries whose name starts with a dot.
+func SkipHidden() ListOption {
+	return func(o *ListOptions) { o.SkipHidden = true }
+}
+
+// List returns the entries below p selected by opts, sorted as by Paths.Sort.
+// Symlinks are classified by what they point to, so a link to a directory is
+// listed as a directory, but they are never descended into. p itself may be a
+// symlink to a directory.
+func (p Path) List(opts ListOptions) (Paths, error) {
+	if opts.Pattern != "" {
+		if err := validPattern(opts.Pattern); err != nil {
+			return nil, errz.E(err, "invalid pattern").With("pattern", opts.Pattern)
+		}
+	}
+	if !p.IsDir() {
+		return nil, errz.E("not a directory").With("path", p)
+	}
+	maxDepth := opts.MaxDepth
+	if !opts.Recursive {
+		maxDepth = 1
+	}
+
+	// A trailing separator makes the walk start inside a symlinked directory.
+	root := string(p)
+	if p.IsSymlink() {
+		root += string(filepath.Separator)
+	}
+
+	var paths Paths
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if opts.SkipHidden && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if opts.Pattern == "" || matchPath(opts.Pattern, rel) {
+			ok, err := listType(Path(path), d, opts.Type)
+			if err != nil {
+				return err
+			}
+			if ok {
+				paths = append(paths, Path(path))
+			}
+		}
+
+		if d.IsDir() && maxDepth > 0 && strings.Count(rel, "/")+1 >= maxDepth {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	paths.Sort()
+	return paths, nil
+}
+
+// listType reports whether the entry at path is of the kind t selects.
+func listType(path Path, d fs.DirEntry, t ListType) (bool, error) {
+	if t == ListAll {
+		return true, nil
+	}
+
+	mode := d.Type()
+	if mode&fs.ModeSymlink != 0 {
+		fi, err := path.Stat()
+		if err != nil {
+			// a dangling link is neither a directory nor a file
+			return false, nil
+		}
+		mode = fi.Mode().Type()
+	}
+	if t == ListDirs {
+		return mode.IsDir(), nil
+	}
+	return mode.IsRegular(), nil
+}
+
+// SubdirNames returns the sorted names of the directories directly inside p.
+// Symlinks are included when they point to a directory.
+func (p Path) SubdirNames(opts ...ListOption) ([]string, error) {
+	return p.names(ListDirs, opts)
+}
+
+// FileNames returns the sorted names of the regular files directly inside p.
+// Symlinks are included when they point to a regular file.
+func (p Path) FileNames(opts ...ListOption) ([]string, error) {
+	return p.names(ListFiles, opts)
+}
+
+func (p Path) names(t ListType, opts []ListOption) ([]string, error) {
+	o := ListOptions{Type: t}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	paths, err := p.List(o)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(paths))
+	for i, path := range paths {
+		names[i] = path.Base().String()
+	}
+	return names, nil
+}
+
+// FilterByMagic returns the regular files directly inside p whose content
+// starts with magic, such as "\x7fELF" for ELF binaries or "#!" for scripts,
+// sorted as by Paths.Sort. Symlinks are followed to regular files. Only
+// len(magic) bytes are read from each file; directories, special files and
+// files shorter than magic are skipped. Files that cannot be read are left
+// out and their errors returned together with the matches found.
+func (p Path) FilterByMagic(magic []byte) (Paths, error) {
+	files, err := p.List(ListOptions{Type: ListFiles})
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		matches Paths
+		errs    []error
+	)
+	buf := make([]byte, len(magic))
+	for _, f := range files {
+		n, err := f.ReadInto(buf)
+		if err != nil {
+			errs = append(errs, errz.E(err, "read file header").With("path", f))
+			continue
+		}
+		if n == len(magic) && bytes.Equal(buf, magic) {
+			matches = append(matches, f)
+		}
+	}
+	return matches, errz.Join(errs...)
+}
+
+// ReadDirPage returns up to limit entries of the directory, sorted by name,
+// starting at offset, along with the total number of entries. A limit of zero
+// or less returns all entries from offset on.
+//
+// Every call reads the whole directory, and pages are computed independently:
+// entries created or removed between calls shift later pages, so an entry may
+// be skipped or returned twice. Use ReadDirNamesAfter for huge directories.
+func (p Path) ReadDirPage(offset, limit int) (entries []fs.DirEntry, total int, err error) {
+	if offset < 0 {
+		return nil, 0, errz.E("negative offset").With("offset", offset)
+	}
+
+	all, err := p.ReadDir()
+	if err != nil {
+		return nil, 0, err
+	}
+	total = len(all)
+	offset = min(offset, total)
+	end := total
+	if limit > 0 {
+		end = min(offset+limit, total)
+	}
+	return all[offset:end], total, nil
+}
+
+// readDirBatch is the number of names ReadDirNamesAfter reads at a time.
+const readDirBatch = 1024
+
+// ReadDirNamesAfter returns up to limit names of the directory's entries, in
+// sorted order, that sort after cursor. Pass an empty cursor for the first page
+// and the returned next cursor for the following ones; next is empty on the
+// last page.
+//
+// The directory is streamed in batches and only limit names are kept, so
+// memory stays bounded however large the directory is. Since the cursor is a
+// position in name order rather than an index, entries removed between calls
+// never shift later pages; entries created behind the cursor are not seen.
+func (p Path) ReadDirNamesAfter(cursor string, limit int) (names []string, next string, err error) {
+	if limit <= 0 {
+		return nil, "", errz.E("limit must be positive").With("limit", limit)
+	}
+
+	f, err := os.Open(string(p))
+	if err != nil {
+		return nil, "", errz.E(err, "open directory").With("path", p)
+	}
+	defer f.Close()
+
+	// Keep the limit+1 smallest names after the cursor; the extra one tells
+	// whether another page follows.
+	keep := limit + 1
+	for {
+		batch, err := f.Readdirnames(readDirBatch)
+		for _, name := range batch {
+			if name > cursor {
+				names = append(names, name)
+			}
+		}
+		if len(names) > 2*keep {
+			slices.Sort(names)
+			names = names[:keep]
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, "", errz.E(err, "read directory").With("path", p)
+		}
+	}
+
+	slices.Sort(names)
+	if len(names) > limit {
+		names = names[:limit]
+		next = names[limit-1]
+	}
+	return names, next, nil
+}
@@ -0,0 +1,252 @@
+package ppath
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSubdirNames(t *testing.T) {
+	dir := New(t.TempDir())
+	for _, name := range []string{"work/x", "default/x", ".cache/x", "readme.md", ".env"} {
+		if err := dir.Join(name).WriteFile(nil); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	if err := os.Symlink("work", dir.Join("linked").String()); err != nil {
+		t.Fatalf("os.Symlink: %v", err)
+	}
+	if err := os.Symlink("missing", dir.Join("dangling").String()); err != nil {
+		t.Fatalf("os.Symlink: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		list     func(...ListOption) ([]string, error)
+		opts     []ListOption
+		expected []string
+	}{
+		{"Subdirs", dir.SubdirNames, nil, []string{".cache", "default", "linked", "work"}},
+		{"SubdirsSkipHidden", dir.SubdirNames, []ListOption{SkipHidden()}, []string{"default", "linked", "work"}},
+		{"Files", dir.FileNames, nil, []string{".env", "readme.md"}},
+		{"FilesSkipHidden", dir.FileNames, []ListOption{SkipHidden()}, []string{"readme.md"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names, err := tt.list(tt.opts...)
+			if err != nil {
+				t.Fatalf("list: %v", err)
+			}
+			if !slices.Equal(names, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, names)
+			}
+		})
+	}
+
+	if _, err := dir.Join("readme.md").SubdirNames(); err == nil {
+		t.Errorf("expected error for a file, got nil")
+	}
+}
+
+func TestList(t *testing.T) {
+	dir := New(t.TempDir())
+	for _, name := range []string{"a.go", "b.txt", ".hidden.go", "pkg/c.go", "pkg/sub/d.go", ".git/e.go"} {
+		if err := dir.Join(name).WriteFile(nil); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	rel := func(paths Paths) []string {
+		var names []string
+		for _, p := range paths {
+			r, err := p.Rel(dir)
+			if err != nil {
+				t.Fatalf("Rel: %v", err)
+			}
+			names = append(names, filepath.ToSlash(r.String()))
+		}
+		return names
+	}
+
+	tests := []struct {
+		name     string
+		opts     ListOptions
+		expected []string
+	}{
+		{"Default", ListOptions{}, []string{".git", ".hidden.go", "a.go", "b.txt", "pkg"}},
+		{"Files", ListOptions{Type: ListFiles}, []string{".hidden.go", "a.go", "b.txt"}},
+		{"Dirs", ListOptions{Type: ListDirs, SkipHidden: true}, []string{"pkg"}},
+		{"Recursive", ListOptions{Recursive: true, SkipHidden: true}, []string{"a.go", "b.txt", "pkg", "pkg/c.go", "pkg/sub", "pkg/sub/d.go"}},
+		{"MaxDepth", ListOptions{Recursive: true, MaxDepth: 2, Type: ListFiles, SkipHidden: true}, []string{"a.go", "b.txt", "pkg/c.go"}},
+		{"Pattern", ListOptions{Recursive: true, Pattern: "**/*.go"}, []string{".git/e.go", ".hidden.go", "a.go", "pkg/c.go", "pkg/sub/d.go"}},
+		{"PatternTopLevel", ListOptions{Recursive: true, Pattern: "*.go", SkipHidden: true}, []string{"a.go"}},
+		{"MaxDepthIgnoredWithoutRecursive", ListOptions{MaxDepth: 3, Type: ListFiles, SkipHidden: true}, []string{"a.go", "b.txt"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths, err := dir.List(tt.opts)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if got := rel(paths); !slices.Equal(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	link := New(t.TempDir(), "link")
+	if err := os.Symlink(dir.Join("pkg").String(), link.String()); err != nil {
+		t.Fatalf("os.Symlink: %v", err)
+	}
+	paths, err := link.List(ListOptions{Type: ListFiles})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if expected := (Paths{link.Join("c.go")}); !slices.Equal(paths, expected) {
+		t.Errorf("expected %v, got %v", expected, paths)
+	}
+
+	if _, err := dir.List(ListOptions{Pattern: "["}); err == nil {
+		t.Errorf("expected error for bad pattern, got nil")
+	}
+	if _, err := dir.Join("a.go").List(ListOptions{}); err == nil {
+		t.Errorf("expected error for a file, got nil")
+	}
+}
+
+func TestFilterByMagic(t *testing.T) {
+	dir := New(t.TempDir())
+	files := map[string]string{
+		"tool":        "\x7fELF\x02\x01\x01rest of the binary",
+		"lib.so":      "\x7fELF\x02",
+		"run.sh":      "#!/bin/sh\necho hi\n",
+		"short":       "\x7fEL",
+		"empty":       "",
+		"image.png":   "\x89PNG\r\n\x1a\n",
+		"sub/nested":  "\x7fELF nested",
+		"elf-but.txt": "not \x7fELF",
+	}
+	for name, content := range files {
+		if err := dir.Join(name).WriteFile([]byte(content)); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	if err := os.Symlink(dir.Join("tool").String(), dir.Join("link").String()); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+
+	tests := []struct {
+		magic    string
+		expected []string
+	}{
+		{"\x7fELF", []string{"lib.so", "link", "tool"}},
+		{"#!", []string{"run.sh"}},
+		{"\x89PNG\r\n\x1a\n", []string{"image.png"}},
+		{"GIF8", nil},
+	}
+	for _, tt := range tests {
+		got, err := dir.FilterByMagic([]byte(tt.magic))
+		if err != nil {
+			t.Fatalf("FilterByMagic(%q): %v", tt.magic, err)
+		}
+		var names []string
+		for _, p := range got {
+			names = append(names, p.Base().String())
+		}
+		if !slices.Equal(names, tt.expected) {
+			t.Errorf("FilterByMagic(%q): expected %v, got %v", tt.magic, tt.expected, names)
+		}
+	}
+
+	if _, err := dir.Join("tool").FilterByMagic([]byte("#!")); err == nil {
+		t.Errorf("expected error for a file, got nil")
+	}
+}
+
+func TestReadDirPage(t *testing.T) {
+	dir := New(t.TempDir())
+	for _, name := range []string{"c", "a", "e", "b", "d"} {
+		if err := dir.Join(name).WriteFile(nil); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	tests := []struct {
+		offset, limit int
+		expected      []string
+	}{
+		{0, 2, []string{"a", "b"}},
+		{2, 2, []string{"c", "d"}},
+		{4, 2, []string{"e"}},
+		{7, 2, []string{}},
+		{1, 0, []string{"b", "c", "d", "e"}},
+	}
+	for _, tt := range tests {
+		entries, total, err := dir.ReadDirPage(tt.offset, tt.limit)
+		if err != nil {
+			t.Fatalf("ReadDirPage(%d, %d): %v", tt.offset, tt.limit, err)
+		}
+		if total != 5 {
+			t.Errorf("expected total 5, got %d", total)
+		}
+		names := []string{}
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		if !slices.Equal(names, tt.expected) {
+			t.Errorf("ReadDirPage(%d, %d): expected %v, got %v", tt.offset, tt.limit, tt.expected, names)
+		}
+	}
+
+	if _, _, err := dir.ReadDirPage(-1, 2); err == nil {
+		t.Errorf("expected error for a negative offset, got nil")
+	}
+	if _, _, err := dir.Join("missing").ReadDirPage(0, 2); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
+func TestReadDirNamesAfter(t *testing.T) {
+	dir := New(t.TempDir())
+	var expected []string
+	for i := range 3000 {
+		name := fmt.Sprintf("f%04d", i)
+		if err := dir.Join(name).WriteFile(nil); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		expected = append(expected, name)
+	}
+
+	var got []string
+	cursor, pages := "", 0
+	for {
+		names, next, err := dir.ReadDirNamesAfter(cursor, 700)
+		if err != nil {
+			t.Fatalf("ReadDirNamesAfter: %v", err)
+		}
+		got = append(got, names...)
+		pages++
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if pages != 5 {
+		t.Errorf("expected 5 pages, got %d", pages)
+	}
+	if !slices.Equal(got, expected) {
+		t.Errorf("expected all %d names in order, got %d", len(expected), len(got))
+	}
+
+	names, next, err := dir.ReadDirNamesAfter("f2999", 10)
+	if err != nil || len(names) != 0 || next != "" {
+		t.Errorf("expected empty last page, got %v, %q, error: %v", names, next, err)
+	}
+	if _, _, err := dir.ReadDirNamesAfter("", 0); err == nil {
+		t.Errorf("expected error for a zero limit, got nil")
+	}
+}
@@ -0,0 +1,23 @@
+//go:build windows
+
+package ppath
+
+import (
+	"io"
+	"os"
+)
+
+// mapFile reads the file into memory. Windows keeps a mapped file from being
+// replaced or deleted, which would defeat refreshing the cache, so the
+// contents are copied instead.
+func mapFile(f *os.File, size int64) ([]byte, error) {
+	data := make([]byte, size)
+	if _, err := io.ReadFull(f, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func unmapFile([]byte) error {
+	return nil
+}
@@ -1,7 +1,10 @@
 package ppath
 
 import (
+	"bytes"
+	"context"
 	"crypto/md5"
+	"crypto/rand"
 	"crypto/sha1"
 	"crypto/sha256"
 	"encoding/hex"
@@ -11,12 +14,20 @@ import (
 	"hash"
 	"io"
 	"io/fs"
+	"iter"
+	"math"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/maa3x/errz"
 	"github.com/shirou/gopsutil/v4/disk"
@@ -28,6 +39,16 @@ func New(v ...string) Path {
 	return Path(filepath.Join(v...))
 }
 
+// NewExpand is like New but expands environment variables in each segment
+// before joining them. See Path.ExpandEnv.
+func NewExpand(v ...string) Path {
+	s := make([]string, len(v))
+	for i := range v {
+		s[i] = os.ExpandEnv(v[i])
+	}
+	return New(s...)
+}
+
 // ThisFile retrieves the path of the source file from which it was invoked.
 func ThisFile() Path {
 	_, f, _, _ := runtime.Caller(1)
@@ -44,6 +65,12 @@ func WD() Path {
 	return New(v)
 }
 
+// Roots returns the filesystem roots: "/" on Unix and the drive roots that
+// are ready (`C:\`, `D:\`, ...) on Windows.
+func Roots() ([]Path, error) {
+	return roots()
+}
+
 // ThisDir retrieves the path of the directory containing the source file from which it was invoked.
 func ThisDir() Path {
 	_, f, _, ok := runtime.Caller(1)
@@ -54,6 +81,17 @@ func ThisDir() Path {
 	return WD()
 }
 
+// Relative joins elem onto the directory of the source file from which it was
+// invoked, so fixtures next to a test file can be found whatever the working
+// directory. It falls back to the working directory like ThisDir.
+func Relative(elem ...string) Path {
+	dir := WD()
+	if _, f, _, ok := runtime.Caller(1); ok {
+		dir = New(f).Dir()
+	}
+	return dir.Join(elem...)
+}
+
 func (p Path) String() string {
 	return string(p)
 }
@@ -104,6 +142,69 @@ func (p Path) NthParent(n int) Path {
 	return v
 }
 
+// NthParentOK is like NthParent but reports false when n exceeds the number of
+// components of the cleaned path, instead of stopping at the root.
+func (p Path) NthParentOK(n int) (Path, bool) {
+	if n < 0 || n > len(p.components()) {
+		return p, false
+	}
+	return p.Clean().NthParent(n), true
+}
+
+// Root returns the root of an absolute path, such as / or C:\. For a relative
+// path it returns only its volume name, which is empty on Unix.
+func (p Path) Root() Path {
+	volume, rest := p.SplitVolume()
+	if rest != "" && os.IsPathSeparator(rest[0]) {
+		return volume + Path(filepath.Separator)
+	}
+	return volume
+}
+
+// TrimPrefix removes prefix from the start of p, comparing whole cleaned
+// components so that a/b is not a prefix of a/bc. The remainder is returned
+// as a relative path, or "." if nothing remains. If prefix does not match,
+// p is returned unchanged.
+func (p Path) TrimPrefix(prefix Path) Path {
+	if p.Root() != prefix.Root() {
+		return p
+	}
+
+	parts, pre := p.components(), prefix.components()
+	if len(pre) > len(parts) || !slices.Equal(parts[:len(pre)], pre) {
+		return p
+	}
+	return New(parts[len(pre):]...).Clean()
+}
+
+// TrimSuffix removes suffix from the end of p, comparing whole cleaned
+// components. The root of p is kept, and "." is returned if nothing remains of
+// a relative path. If suffix does not match or is absolute, p is returned
+// unchanged.
+func (p Path) TrimSuffix(suffix Path) Path {
+	if suffix.Root() != "" {
+		return p
+	}
+
+	parts, suf := p.components(), suffix.components()
+	if len(suf) > len(parts) || !slices.Equal(parts[len(parts)-len(suf):], suf) {
+		return p
+	}
+	return p.Root().Join(parts[:len(parts)-len(suf)]...).Clean()
+}
+
+// components returns the cleaned components of p, without its volume name.
+func (p Path) components() []string {
+	_, rest := p.Clean().SplitVolume()
+	parts := strings.FieldsFunc(string(rest), func(r rune) bool {
+		return r < utf8.RuneSelf && os.IsPathSeparator(uint8(r))
+	})
+	if len(parts) == 1 && parts[0] == "." {
+		return nil
+	}
+	return parts
+}
+
 func (p Path) Ext() Path {
 	return Path(filepath.Ext(string(p)))
 }
@@ -117,6 +218,37 @@ func (p Path) Segments() []string {
 	return filepath.SplitList(string(p))
 }
 
+// SegmentsSeq yields the components of the cleaned path one at a time, without
+// building a slice, so matching can stop at the first mismatch. The root is not
+// a component: "/users/42/posts", "users/42/posts" and "/users//42/posts/"
+// all yield "users", "42", "posts", and "/" and "." yield nothing. Leading
+// ".." components of a relative path are kept. On Windows a volume name such
+// as "C:" is yielded first, and both slashes separate components.
+func (p Path) SegmentsSeq() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		clean := filepath.Clean(string(p))
+		vol := filepath.VolumeName(clean)
+		if vol != "" && !yield(vol) {
+			return
+		}
+
+		rest := clean[len(vol):]
+		for rest != "" {
+			i := strings.IndexFunc(rest, func(r rune) bool { return r == '/' || r == filepath.Separator })
+			if i < 0 {
+				if rest != "." {
+					yield(rest)
+				}
+				return
+			}
+			if i > 0 && !yield(rest[:i]) {
+				return
+			}
+			rest = rest[i+1:]
+		}
+	}
+}
+
 func (p Path) Rel(r Path) (Path, error) {
 	rel, err := filepath.Rel(string(r), string(p))
 	return Path(rel), err
@@ -131,6 +263,21 @@ func (p Path) Abs() (Path, error) {
 	return Path(abs), err
 }
 
+// AbsFrom resolves p against base instead of the working directory. An
+// absolute p is returned unchanged. A relative base is itself made absolute
+// against the working directory first.
+func (p Path) AbsFrom(base Path) (Path, error) {
+	if p.IsAbs() {
+		return p, nil
+	}
+
+	absBase, err := base.Abs()
+	if err != nil {
+		return "", err
+	}
+	return absBase.JoinPath(p), nil
+}
+
 func (p Path) IsChildOf(parent Path) bool {
 	return strings.HasPrefix(string(p), string(parent))
 }
@@ -140,7 +287,7 @@ func (p Path) IsParentOf(child Path) bool {
 }
 
 func (p Path) Delete() error {
-	return os.RemoveAll(string(p))
+	return filesystem().RemoveAll(string(p))
 }
 
 func (p Path) Remove() error {
@@ -151,7 +298,7 @@ func (p Path) Rename(n string) error {
 	if err := Path(n).Dir().MkdirIfNotExist(); err != nil {
 		return fmt.Errorf("create parent directory: %w", err)
 	}
-	return os.Rename(string(p), n)
+	return filesystem().Rename(string(p), n)
 }
 
 func (p Path) Copy(dst Path) error {
@@ -162,19 +309,23 @@ func (p Path) Copy(dst Path) error {
 		return os.CopyFS(string(dst), os.DirFS(string(p)))
 	}
 
-	src, err := p.Open()
+	if dst.IsDir() {
+		dst = dst.JoinPath(p.Base())
+	}
+	return p.copyFile(dst)
+}
+
+func (p Path) copyFile(dst Path) error {
+	src, err := p.OpenHandle()
 	if err != nil {
 		return fmt.Errorf("open source file: %w", err)
 	}
 	defer src.Close()
 
-	if dst.IsDir() {
-		dst = dst.JoinPath(p.Base())
-	}
 	if err := dst.Dir().MkdirIfNotExist(); err != nil {
 		return fmt.Errorf("create parent directory: %w", err)
 	}
-	dest, err := dst.OpenFile(os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	dest, err := dst.OpenFileHandle(os.O_WRONLY|os.O_CREATE|os.O_TRUNC, defaultFilePerm())
 	if err != nil {
 		return err
 	}
@@ -184,13 +335,44 @@ func (p Path) Copy(dst Path) error {
 	return err
 }
 
+// ConflictPolicy decides what happens when a file is moved or copied onto a
+// destination file that already exists.
+type ConflictPolicy int
+
+const (
+	// ConflictOverwrite replaces the existing destination file.
+	ConflictOverwrite ConflictPolicy = iota
+	// ConflictSkip keeps the existing destination file.
+	ConflictSkip
+	// ConflictKeepNewer keeps whichever file has the newer modification time.
+	ConflictKeepNewer
+	// ConflictRename keeps both files by giving the incoming one a free name
+	// with a counter appended, such as "file (1).txt".
+	ConflictRename
+)
+
 // MergeMove moves a file or directory from path p to dst.
 //   - If dst doesn't exist: performs a straight move
-//   - If p is a file and dst is a directory: moves p into dst
-//   - If p is a file and dst is a file: replaces dst with p
+//   - If p is a file or symlink and dst is a directory: moves p into dst
+//   - If p is a file or symlink and dst is a file: replaces dst with p
 //   - If p is a directory and dst is a directory: recursively merges contents
+//
+// Symlinks are moved as links and never followed. Other special files such as
+// sockets, devices and named pipes are rejected with an error naming them.
+// Entries that cannot be renamed because they are on another device are copied
+// with their permissions and modification times and then removed. If an entry
+// fails, the entries moved before it stay at the destination and the error
+// names the failing entry.
 func (p Path) MergeMove(dst Path) error {
-	if !p.Exists() {
+	return p.MergeMoveWith(dst, ConflictOverwrite)
+}
+
+// MergeMoveWith is like MergeMove but resolves existing destination files
+// according to onConflict. Source files that are not moved because of the
+// policy are left in place, and so are the source directories containing them.
+func (p Path) MergeMoveWith(dst Path, onConflict ConflictPolicy) error {
+	fi, err := filesystem().Lstat(string(p))
+	if err != nil {
 		return errz.E("source file does not exist")
 	}
 
@@ -198,38 +380,41 @@ func (p Path) MergeMove(dst Path) error {
 		if err := dst.Dir().MkdirIfNotExist(); err != nil {
 			return errz.E(err, "create parent directory")
 		}
-		if err := os.Rename(string(p), string(dst)); err != nil {
+		if err := renameOrCopy(p, dst); err != nil {
 			return errz.E(err, "rename file")
 		}
 		return nil
 	}
 
-	if p.IsRegular() {
+	if fi.Mode().IsRegular() || fi.Mode()&fs.ModeSymlink != 0 {
 		if dst.IsDir() {
 			dst = dst.JoinPath(p.Base())
-			if err := os.Rename(string(p), string(dst)); err != nil {
-				return errz.E(err, "rename file")
+			if !dst.Exists() {
+				if err := renameOrCopy(p, dst); err != nil {
+					return errz.E(err, "rename file")
+				}
+				return nil
 			}
-			return nil
 		}
 		if !dst.IsRegular() {
-			return errz.E("destination is not a regular file")
+			return errz.E("destination is not a regular file").With("path", dst)
 		}
 
-		if err := dst.Delete(); err != nil {
-			return errz.E(err, "delete old file")
+		dst, ok, err := resolveConflict(fi, dst, onConflict)
+		if err != nil || !ok {
+			return err
 		}
-		if err := os.Rename(string(p), string(dst)); err != nil {
+		if err := renameOrCopy(p, dst); err != nil {
 			return errz.E(err, "rename file")
 		}
 		return nil
 	}
 
-	if !p.IsDir() {
-		return errz.E("source must be a regular file or directory")
+	if !fi.IsDir() {
+		return errz.E("unsupported file type").With("path", p).With("type", fi.Mode().Type())
 	}
 	if !dst.IsDir() {
-		return errz.E("destination is not a directory")
+		return errz.E("destination is not a directory").With("path", dst)
 	}
 
 	entries, err := p.ReadDir()
@@ -240,14 +425,170 @@ func (p Path) MergeMove(dst Path) error {
 		entryName := entries[i].Name()
 		srcPath := p.Join(entryName)
 		dstPath := dst.Join(entryName)
-		if err := srcPath.MergeMove(dstPath); err != nil {
-			return errz.E(err, "move file", "name", entryName)
+		if err := srcPath.MergeMoveWith(dstPath, onConflict); err != nil {
+			return errz.E(err, "move file").With("name", entryName)
 		}
 	}
 
+	if !p.IsEmpty() {
+		return nil
+	}
 	return p.Delete()
 }
 
+// resolveConflict applies policy to an incoming file described by src whose
+// destination dst already exists. It returns the path the file should be
+// written to, or false if the file should not be written at all. For
+// ConflictOverwrite the existing destination is removed.
+func resolveConflict(src fs.FileInfo, dst Path, policy ConflictPolicy) (Path, bool, error) {
+	switch policy {
+	case ConflictSkip:
+		return dst, false, nil
+	case ConflictRename:
+		return dst.freeName(), true, nil
+	case ConflictKeepNewer:
+		dfi, err := dst.Stat()
+		if err != nil {
+			return dst, false, errz.E(err, "stat destination file")
+		}
+		if !src.ModTime().After(dfi.ModTime()) {
+			return dst, false, nil
+		}
+	case ConflictOverwrite:
+	default:
+		return dst, false, errz.E("unknown conflict policy").With("policy", policy)
+	}
+
+	if err := dst.Delete(); err != nil {
+		return dst, false, errz.E(err, "delete old file")
+	}
+	return dst, true, nil
+}
+
+// freeName returns p, or the first sibling named "<name> (n)<ext>" that does
+// not exist yet.
+func (p Path) freeName() Path {
+	if _, err := filesystem().Lstat(string(p)); err != nil {
+		return p
+	}
+
+	base := p.BaseWithoutExt()
+	ext := strings.TrimPrefix(string(p.Base()), string(base))
+	for i := 1; ; i++ {
+		candidate := p.Dir().Join(fmt.Sprintf("%s (%d)%s", base, i, ext))
+		if _, err := filesystem().Lstat(string(candidate)); err != nil {
+			return candidate
+		}
+	}
+}
+
+// UniqueChild returns a path in directory p named prefix, a random string and
+// suffix, that did not exist when it was checked. Unlike os.CreateTemp nothing
+// is created, so another process may still claim the name between the check
+// and the caller's use of it; with 64 random bits that is only a concern when
+// an adversary can predict or race for names in p.
+func (p Path) UniqueChild(prefix, suffix string) (Path, error) {
+	if strings.ContainsAny(prefix+suffix, `/`+string(filepath.Separator)) {
+		return "", errz.E("prefix or suffix contains a path separator").
+			With("prefix", prefix).With("suffix", suffix)
+	}
+
+	const attempts = 100
+	for range attempts {
+		var b [8]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return "", errz.E(err, "generate random name")
+		}
+
+		candidate := p.Join(prefix + hex.EncodeToString(b[:]) + suffix)
+		_, err := filesystem().Lstat(string(candidate))
+		if errors.Is(err, fs.ErrNotExist) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", errz.E(err, "stat candidate").With("path", candidate)
+		}
+	}
+	return "", errz.E("no unused name found").With("dir", p).With("attempts", attempts)
+}
+
+// RenameEach renames every immediate child of the directory p to the name fn
+// returns for it, and returns how many were renamed. An empty or unchanged
+// name leaves the child alone. Every new name must be a safe single segment as
+// with IsSafeSegment, and must not collide, ignoring case, with another new
+// name or with a child that keeps its name; all of this is checked before
+// anything is renamed. The children are first moved to temporary names and
+// then to their targets, so swaps, chains and case-only renames such as "Foo"
+// to "foo" work on case-insensitive filesystems too. If moving to the
+// temporary names fails, the children are moved back and nothing is renamed;
+// a child whose final rename fails is left under its temporary name, which
+// the returned error reports.
+func (p Path) RenameEach(fn func(name string) string) (int, error) {
+	entries, err := p.ReadDir()
+	if err != nil {
+		return 0, err
+	}
+
+	type rename struct {
+		from, to string
+		tmp      Path
+	}
+	var plan []rename
+	kept := make(map[string]string)
+	for _, e := range entries {
+		name := e.Name()
+		to := fn(name)
+		if to == "" || to == name {
+			kept[strings.ToLower(name)] = name
+			continue
+		}
+		if !IsSafeSegment(to) {
+			return 0, errz.E("invalid new name").With("name", name).With("new", to)
+		}
+		plan = append(plan, rename{from: name, to: to})
+	}
+
+	targets := make(map[string]string, len(plan))
+	for _, r := range plan {
+		key := strings.ToLower(r.to)
+		if other, ok := kept[key]; ok {
+			return 0, errz.E("new name collides with an existing entry").
+				With("name", r.from).With("new", r.to).With("existing", other)
+		}
+		if other, ok := targets[key]; ok {
+			return 0, errz.E("new names collide").
+				With("name", r.from).With("other", other).With("new", r.to)
+		}
+		targets[key] = r.from
+	}
+
+	for i := range plan {
+		tmp, err := p.UniqueChild(".rename-", "")
+		if err == nil {
+			err = filesystem().Rename(string(p.Join(plan[i].from)), string(tmp))
+		}
+		if err != nil {
+			// Nothing has its new name yet, so put the moved children back.
+			for _, r := range plan[:i] {
+				filesystem().Rename(string(r.tmp), string(p.Join(r.from)))
+			}
+			return 0, errz.E(err, "move to temporary name").With("path", p.Join(plan[i].from))
+		}
+		plan[i].tmp = tmp
+	}
+
+	renamed := 0
+	var errs []error
+	for _, r := range plan {
+		if err := filesystem().Rename(string(r.tmp), string(p.Join(r.to))); err != nil {
+			errs = append(errs, errz.E(err, "rename").With("from", r.from).With("to", r.to).With("tmp", r.tmp))
+			continue
+		}
+		renamed++
+	}
+	return renamed, errz.Join(errs...)
+}
+
 func (p Path) Move(dst Path) error {
 	if !p.IsExist() {
 		return errors.New("source file does not exist")
@@ -260,6 +601,28 @@ func (p Path) Move(dst Path) error {
 	return p.Rename(dst.String())
 }
 
+// MoveInto moves p into the directory dir under its base name, creating dir
+// if needed, and returns the new path. An existing file of that name is
+// replaced as with Move. When dir is on a different device, p is copied with
+// its permissions and times and then removed.
+func (p Path) MoveInto(dir Path) (Path, error) {
+	if _, err := p.LStat(); err != nil {
+		return "", errz.E(err, "stat source").With("path", p)
+	}
+	if fi, err := dir.Stat(); err == nil && !fi.IsDir() {
+		return "", errz.E("destination is not a directory").With("path", dir)
+	}
+	if err := dir.MkdirIfNotExist(); err != nil {
+		return "", errz.E(err, "create destination directory").With("path", dir)
+	}
+
+	dst := dir.JoinPath(p.Base())
+	if err := renameOrCopy(p, dst); err != nil {
+		return "", errz.E(err, "move").With("path", p).With("destination", dst)
+	}
+	return dst, nil
+}
+
 func (p Path) Truncate() error {
 	if p.IsRegular() {
 		return errz.If(os.Truncate(string(p), 0), "truncate file")
@@ -275,46 +638,241 @@ func (p Path) Truncate() error {
 	return errz.E("unsupported target")
 }
 
+// OpenFile opens the file optimistically and only falls back to creating the
+// parent directory, or to a Stat, when the open itself fails. It always opens
+// the file on the OS filesystem; OpenFileHandle honors SetFileSystem.
 func (p Path) OpenFile(flag int, perm os.FileMode) (*os.File, error) {
-	if p.IsDir() {
-		return nil, errors.New("can not open a directory")
-	}
-	if err := p.Dir().MkdirIfNotExist(); err != nil {
-		return nil, fmt.Errorf("create parent directory: %w", err)
+	f, err := p.openFile(osFS{}, flag, perm)
+	if err != nil {
+		return nil, err
 	}
-	return os.OpenFile(string(p), flag, perm)
+	return f.(*os.File), nil
 }
 
 func (p Path) Open() (*os.File, error) {
 	return p.OpenFile(os.O_RDONLY, 0)
 }
 
+// OpenFileHandle is like OpenFile, but opens the file through the backend set
+// with SetFileSystem. With the default backend the handle is an *os.File.
+func (p Path) OpenFileHandle(flag int, perm os.FileMode) (File, error) {
+	return p.openFile(filesystem(), flag, perm)
+}
+
+// OpenHandle opens the file for reading through the backend set with
+// SetFileSystem.
+func (p Path) OpenHandle() (File, error) {
+	f, err := filesystem().Open(string(p))
+	if err != nil {
+		return nil, err
+	}
+	return p.rejectDir(asFile(f, string(p)))
+}
+
+func (p Path) openFile(fsys FileSystem, flag int, perm os.FileMode) (File, error) {
+	f, err := fsys.OpenFile(string(p), flag, perm)
+	if err != nil && flag&os.O_CREATE != 0 && errors.Is(err, fs.ErrNotExist) {
+		if err := p.Dir().MkdirIfNotExist(); err != nil {
+			return nil, fmt.Errorf("create parent directory: %w", err)
+		}
+		f, err = fsys.OpenFile(string(p), flag, perm)
+	}
+	if err != nil {
+		if p.IsDir() {
+			return nil, errors.New("can not open a directory")
+		}
+		return nil, err
+	}
+
+	// Read-only opens of a directory succeed, so check the opened handle.
+	if flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return p.rejectDir(f)
+	}
+	return f, nil
+}
+
+// rejectDir closes f and fails if it is a directory, since read-only opens of
+// a directory succeed.
+func (p Path) rejectDir(f File) (File, error) {
+	if fi, err := f.Stat(); err == nil && fi.IsDir() {
+		f.Close()
+		return nil, errors.New("can not open a directory")
+	}
+	return f, nil
+}
+
+// Reader opens the file for reading with OpenHandle, so the returned value is
+// an *os.File unless SetFileSystem installed another backend.
+func (p Path) Reader() (io.ReadSeekCloser, error) {
+	f, err := p.OpenHandle()
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Writer opens the file for writing with OpenFileHandle, creating it if needed
+// and truncating any existing content. The returned value is an *os.File
+// unless SetFileSystem installed another backend.
+func (p Path) Writer() (io.WriteCloser, error) {
+	f, err := p.OpenFileHandle(os.O_WRONLY|os.O_CREATE|os.O_TRUNC, defaultFilePerm())
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// TeeWriter opens the file for appending, creating it and its parent
+// directories if needed, and returns a writer that writes everything to both
+// the file and w. Data is written to the file first, so it is captured even if
+// writing to w fails. Closing the writer closes the file but not w.
+func (p Path) TeeWriter(w io.Writer) (io.WriteCloser, error) {
+	f, err := p.OpenFileHandle(os.O_WRONLY|os.O_CREATE|os.O_APPEND, defaultFilePerm())
+	if err != nil {
+		return nil, err
+	}
+	return &teeWriter{Writer: io.MultiWriter(f, w), f: f}, nil
+}
+
+type teeWriter struct {
+	io.Writer
+	f File
+}
+
+func (t *teeWriter) Close() error {
+	return t.f.Close()
+}
+
+// AsReader returns a reader that opens the file on the first Read and closes
+// it as soon as a Read returns EOF or an error, so a fully consumed reader
+// needs no cleanup. A reader that is abandoned before EOF must still be closed.
+// The reader is single-use: once the file is closed, further reads return the
+// error that ended it.
+func (p Path) AsReader() io.ReadCloser {
+	return &lazyReader{path: p}
+}
+
+type lazyReader struct {
+	path Path
+	f    File
+	err  error
+}
+
+func (r *lazyReader) Read(b []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	if r.f == nil {
+		f, err := r.path.OpenHandle()
+		if err != nil {
+			r.err = err
+			return 0, err
+		}
+		r.f = f
+	}
+
+	n, err := r.f.Read(b)
+	if err != nil {
+		r.err = err
+		r.f.Close()
+		r.f = nil
+	}
+	return n, err
+}
+
+func (r *lazyReader) Close() error {
+	if r.err == nil {
+		r.err = os.ErrClosed
+	}
+	if r.f == nil {
+		return nil
+	}
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
+
 func (p Path) OpenOrCreate() (*os.File, error) {
-	return p.OpenFile(os.O_RDWR|os.O_CREATE, 0o644)
+	return p.OpenFile(os.O_RDWR|os.O_CREATE, defaultFilePerm())
 }
 
+// Create creates the file for reading and writing and fails if anything
+// already exists at p. The returned error then still matches os.ErrExist.
 func (p Path) Create() (*os.File, error) {
-	if p.IsExist() {
-		return nil, errors.New("already exists")
+	f, err := p.CreateExclusive()
+	if errors.Is(err, fs.ErrExist) {
+		return nil, alreadyExistsError{err}
+	}
+	return f, err
+}
+
+// CreateExclusive creates the file for reading and writing with O_EXCL, so
+// the OS guarantees that exactly one caller creates it. If anything already
+// exists at p, including a dangling symlink, the error matches os.ErrExist.
+// A missing parent directory is created.
+func (p Path) CreateExclusive() (*os.File, error) {
+	const flag = os.O_RDWR | os.O_CREATE | os.O_EXCL
+	f, err := os.OpenFile(string(p), flag, 0o666)
+	if err != nil && errors.Is(err, fs.ErrNotExist) {
+		if err := p.Dir().MkdirIfNotExist(); err != nil {
+			return nil, fmt.Errorf("create parent directory: %w", err)
+		}
+		f, err = os.OpenFile(string(p), flag, 0o666)
 	}
+	return f, err
+}
 
-	if err := p.Dir().MkdirIfNotExist(); err != nil {
-		return nil, fmt.Errorf("create parent directory: %w", err)
+type alreadyExistsError struct{ err error }
+
+func (e alreadyExistsError) Error() string { return "already exists" }
+func (e alreadyExistsError) Unwrap() error { return e.err }
+
+// EnsureEmptyDir makes p an empty directory. A missing directory is created,
+// and an existing one has its contents removed while the directory itself is
+// kept, so unlike Truncate its mode and ownership are preserved. Symlinks
+// inside p are removed, not followed. It fails if p exists but is not a
+// directory.
+func (p Path) EnsureEmptyDir() error {
+	fi, err := p.Stat()
+	if errors.Is(err, fs.ErrNotExist) {
+		return p.MkdirIfNotExist()
+	}
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return errz.E("already exists but not a directory").With("path", p)
 	}
 
-	return os.Create(string(p))
+	entries, err := p.ReadDir()
+	if err != nil {
+		return errz.E(err, "read directory").With("path", p)
+	}
+	var errs []error
+	for _, e := range entries {
+		if err := p.Entry(e).Delete(); err != nil {
+			errs = append(errs, errz.E(err, "remove").With("path", p.Entry(e)))
+		}
+	}
+	return errz.Join(errs...)
 }
 
 func (p Path) MkdirIfNotExist() error {
-	if !p.IsExist() {
-		return os.MkdirAll(string(p), 0o755)
+	return p.MkdirIfNotExistMode(defaultDirPerm())
+}
+
+// MkdirIfNotExistMode is MkdirIfNotExist with perm used for every directory
+// it creates instead of the default set by SetDefaultDirMode.
+func (p Path) MkdirIfNotExistMode(perm os.FileMode) error {
+	err := filesystem().MkdirAll(string(p), perm)
+	if err == nil {
+		return nil
 	}
 
-	if !p.IsDir() {
+	if fi, serr := p.Stat(); serr == nil && !fi.IsDir() {
 		return errors.New("already exists but not a directory")
 	}
-
-	return nil
+	return err
 }
 
 func (p Path) ReadDir() ([]fs.DirEntry, error) {
@@ -322,15 +880,282 @@ func (p Path) ReadDir() ([]fs.DirEntry, error) {
 		return nil, errors.New("not a directory")
 	}
 
-	entries, err := os.ReadDir(string(p))
+	entries, err := filesystem().ReadDir(string(p))
 	if err != nil {
 		return nil, fmt.Errorf("read directory: %w", err)
 	}
 	return entries, nil
 }
 
+// Entry returns the full path of a directory entry read from p.
+func (p Path) Entry(e fs.DirEntry) Path {
+	return p.Join(e.Name())
+}
+
 func (p Path) ReadFile() ([]byte, error) {
-	return os.ReadFile(string(p))
+	return filesystem().ReadFile(string(p))
+}
+
+// ReadString reads the whole file as a string with surrounding whitespace
+// trimmed.
+func (p Path) ReadString() (string, error) {
+	data, err := p.ReadFile()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// ReadInt reads the file as a base 10 integer, ignoring surrounding
+// whitespace. The error for content that is not a number quotes the content.
+func (p Path) ReadInt() (int64, error) {
+	s, err := p.ReadString()
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		const maxQuoted = 64
+		if len(s) > maxQuoted {
+			s = s[:maxQuoted] + "..."
+		}
+		return 0, errz.E(err, "parse integer").With("path", p).With("content", strconv.Quote(s))
+	}
+	return n, nil
+}
+
+// WriteInt replaces the content of the file with n in base 10 followed by a
+// newline. The file is replaced atomically, so readers never see a partial
+// number.
+func (p Path) WriteInt(n int64) error {
+	return p.writeAtomic(append(strconv.AppendInt(nil, n, 10), '\n'), defaultFilePerm())
+}
+
+// writeAtomic writes data to a temporary file next to p and renames it over
+// p, creating the parent directory if needed.
+func (p Path) writeAtomic(data []byte, perm os.FileMode) error {
+	if err := p.Dir().MkdirIfNotExist(); err != nil {
+		return errz.E(err, "create parent directory")
+	}
+	tmp, err := os.CreateTemp(p.Dir().String(), "."+p.Base().String()+".tmp-*")
+	if err != nil {
+		return errz.E(err, "create temporary file")
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errz.E(err, "write temporary file")
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return errz.E(err, "sync temporary file")
+	}
+	if err := tmp.Close(); err != nil {
+		return errz.E(err, "close temporary file")
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return errz.E(err, "set permissions")
+	}
+	if err := os.Rename(tmp.Name(), string(p)); err != nil {
+		return errz.E(err, "rename temporary file").With("path", p)
+	}
+	return nil
+}
+
+// ErrTooLarge is returned by ReadFileLimit when the file exceeds the limit.
+var ErrTooLarge = errors.New("file too large")
+
+// ReadFileLimit reads the whole file like ReadFile, but fails with an error
+// matching ErrTooLarge if it holds more than limit bytes. The size is checked
+// before reading and enforced again while reading, in case the file grows.
+// When the file grows past the limit while being read, the reported size is the
+// number of bytes seen so far. A negative limit is an error.
+func (p Path) ReadFileLimit(limit int64) ([]byte, error) {
+	if limit < 0 {
+		return nil, errz.E("negative limit").With("path", p).With("limit", limit)
+	}
+
+	f, err := p.OpenHandle()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() > limit {
+		return nil, errz.E(ErrTooLarge).With("path", p).With("size", fi.Size()).With("limit", limit)
+	}
+
+	// Read one byte past the limit to tell a file of exactly limit bytes from
+	// one that grew beyond it. No file can exceed math.MaxInt64 bytes, and
+	// adding one would overflow.
+	readLimit := limit
+	if limit < math.MaxInt64 {
+		readLimit++
+	}
+	data := make([]byte, 0, fi.Size()+1)
+	buf := bytes.NewBuffer(data)
+	n, err := buf.ReadFrom(io.LimitReader(f, readLimit))
+	if err != nil {
+		return nil, errz.E(err, "read file").With("path", p)
+	}
+	if n > limit {
+		return nil, errz.E(ErrTooLarge).With("path", p).With("size", n).With("limit", limit)
+	}
+	return buf.Bytes(), nil
+}
+
+// ReadFileTimeout reads the whole file like ReadFile, but gives up after d and
+// returns an error matching context.DeadlineExceeded, so a hung network mount
+// cannot block the caller. Opening and reading happen in a goroutine. On
+// timeout the file is closed, which fails any further reads and interrupts a
+// blocked read on pollable files such as pipes; a read or open already stuck
+// in the kernel on a regular file cannot be interrupted, so that goroutine
+// and its file descriptor stay in flight until the syscall returns.
+func (p Path) ReadFileTimeout(d time.Duration) ([]byte, error) {
+	type result struct {
+		data []byte
+		err  error
+	}
+	done := make(chan result, 1)
+
+	var (
+		mu        sync.Mutex
+		file      File
+		abandoned bool
+	)
+	go func() {
+		f, err := p.OpenHandle()
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		mu.Lock()
+		if abandoned {
+			mu.Unlock()
+			f.Close()
+			return
+		}
+		file = f
+		mu.Unlock()
+
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			err = errz.E(err, "read file").With("path", p)
+		}
+		done <- result{data, err}
+	}()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		return r.data, r.err
+	case <-timer.C:
+		mu.Lock()
+		abandoned = true
+		if file != nil {
+			file.Close()
+		}
+		mu.Unlock()
+		return nil, errz.E(context.DeadlineExceeded, "read file timed out").With("path", p).With("timeout", d)
+	}
+}
+
+// ReadInto reads up to len(buf) bytes from the start of the file into buf and
+// returns the number of bytes read. A file shorter than buf is not an error.
+func (p Path) ReadInto(buf []byte) (int, error) {
+	f, err := p.OpenHandle()
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	n, err := io.ReadFull(f, buf)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		err = nil
+	}
+	return n, err
+}
+
+// ReadFull fills buf with the first len(buf) bytes of the file. It fails with
+// io.ErrUnexpectedEOF, or io.EOF for an empty file, if the file is shorter
+// than buf.
+func (p Path) ReadFull(buf []byte) error {
+	f, err := p.OpenHandle()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.ReadFull(f, buf)
+	return err
+}
+
+// WriteAt writes data to the file at offset off, creating the file and its
+// parent directories if needed and leaving the rest of the content intact.
+// Writing past the end of the file fills the gap with zeros, which is stored
+// sparsely where the filesystem supports it.
+func (p Path) WriteAt(off int64, data []byte) error {
+	if off < 0 {
+		return errz.E("negative offset").With("path", p).With("offset", off)
+	}
+
+	f, err := p.OpenFileHandle(os.O_WRONLY|os.O_CREATE, defaultFilePerm())
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteAt(data, off); err != nil {
+		f.Close()
+		return errz.E(err, "write file").With("path", p)
+	}
+	return f.Close()
+}
+
+// Allocate creates the file and its parent directories if needed and reserves
+// size bytes of disk space for it, extending it to size if it is shorter; a
+// longer file is not truncated. Blocks are truly reserved with fallocate on
+// Linux, F_PREALLOCATE on macOS and the allocation size on Windows, so later
+// writes within size cannot run out of space. Where the filesystem does not
+// support preallocation, the file is only extended, which on most filesystems
+// leaves a sparse hole rather than reserved space. A backend set with
+// SetFileSystem that does not hand out OS files fails with an error matching
+// errors.ErrUnsupported.
+func (p Path) Allocate(size int64) error {
+	if size < 0 {
+		return errz.E("negative size").With("path", p).With("size", size)
+	}
+
+	handle, err := p.OpenFileHandle(os.O_WRONLY|os.O_CREATE, defaultFilePerm())
+	if err != nil {
+		return err
+	}
+	f, ok := handle.(*os.File)
+	if !ok {
+		handle.Close()
+		return errz.E(errors.ErrUnsupported, "preallocate needs an OS file").With("path", p)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return errz.E(err, "stat file").With("path", p)
+	}
+	if err := allocate(f, fi.Size(), size); err != nil && !errors.Is(err, errors.ErrUnsupported) {
+		f.Close()
+		return errz.E(err, "preallocate").With("path", p).With("size", size)
+	}
+	if fi.Size() < size {
+		if err := f.Truncate(size); err != nil {
+			f.Close()
+			return errz.E(err, "extend file").With("path", p).With("size", size)
+		}
+	}
+	return f.Close()
 }
 
 func (p Path) ReadFrom(r io.Reader) error {
@@ -340,32 +1165,47 @@ func (p Path) ReadFrom(r io.Reader) error {
 	}
 	defer dest.Close()
 
-	_, err = dest.ReadFrom(r)
+	_, err = io.Copy(dest, r)
 	return err
 }
 
+// ReadFromPath copies the content of p2 into p, creating p or truncating it
+// if it already exists. Use ReadFrom for create-only semantics.
 func (p Path) ReadFromPath(p2 Path) error {
-	src, err := p2.Open()
+	src, err := p2.OpenHandle()
 	if err != nil {
 		return err
 	}
 	defer src.Close()
 
-	return p.ReadFrom(src)
+	dest, err := p.OpenFileHandle(os.O_WRONLY|os.O_CREATE|os.O_TRUNC, defaultFilePerm())
+	if err != nil {
+		return err
+	}
+	defer dest.Close()
+
+	_, err = io.Copy(dest, src)
+	return err
 }
 
 func (p Path) WriteFile(data []byte) error {
+	return p.WriteFileMode(data, defaultFilePerm())
+}
+
+// WriteFileMode is WriteFile with perm used when the file is created instead
+// of the default set by SetDefaultFileMode.
+func (p Path) WriteFileMode(data []byte, perm os.FileMode) error {
 	if p.IsDir() {
 		return errors.New("can not write to a directory")
 	}
 	if err := p.Dir().MkdirIfNotExist(); err != nil {
 		return fmt.Errorf("create parent directory: %w", err)
 	}
-	return os.WriteFile(string(p), data, 0o644)
+	return filesystem().WriteFile(string(p), data, perm)
 }
 
 func (p Path) WriteJSON(v any) error {
-	f, err := p.OpenFile(os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	f, err := p.OpenFileHandle(os.O_WRONLY|os.O_CREATE|os.O_TRUNC, defaultFilePerm())
 	if err != nil {
 		return errz.E(err, "open file")
 	}
@@ -375,17 +1215,19 @@ func (p Path) WriteJSON(v any) error {
 }
 
 func (p Path) WriteTo(w io.Writer) (int64, error) {
-	src, err := p.Open()
+	src, err := p.OpenHandle()
 	if err != nil {
 		return 0, err
 	}
 	defer src.Close()
 
-	return src.WriteTo(w)
+	return io.Copy(w, src)
 }
 
+// WriteToPath copies the content of p into p2, creating p2 or truncating it
+// if it already exists.
 func (p Path) WriteToPath(p2 Path) error {
-	dest, err := p2.Create()
+	dest, err := p2.OpenFileHandle(os.O_WRONLY|os.O_CREATE|os.O_TRUNC, defaultFilePerm())
 	if err != nil {
 		return err
 	}
@@ -407,6 +1249,7 @@ func (p Path) IsValid() bool {
 	return fs.ValidPath(string(p))
 }
 
+// IsRegular reports whether p is a regular file, following symlinks.
 func (p Path) IsRegular() bool {
 	fi, err := p.Stat()
 	if err != nil {
@@ -415,6 +1258,7 @@ func (p Path) IsRegular() bool {
 	return fi.Mode().IsRegular()
 }
 
+// IsDir reports whether p is a directory, following symlinks.
 func (p Path) IsDir() bool {
 	fi, err := p.Stat()
 	if err != nil {
@@ -423,14 +1267,26 @@ func (p Path) IsDir() bool {
 	return fi.IsDir()
 }
 
+// LIsDir is like IsDir but does not follow symlinks, so it reports false for
+// a symlink to a directory.
+func (p Path) LIsDir() bool {
+	fi, err := p.LStat()
+	if err != nil {
+		return false
+	}
+	return fi.IsDir()
+}
+
+// IsSymlink reports whether p itself is a symlink. It does not follow it.
 func (p Path) IsSymlink() bool {
-	fi, err := os.Lstat(string(p))
+	fi, err := p.LStat()
 	if err != nil {
 		return false
 	}
 	return fi.Mode()&fs.ModeSymlink != 0
 }
 
+// IsDev reports whether p is a device file, following symlinks.
 func (p Path) IsDev() bool {
 	fi, err := p.Stat()
 	if err != nil {
@@ -439,6 +1295,8 @@ func (p Path) IsDev() bool {
 	return fi.Mode()&fs.ModeDevice != 0
 }
 
+// IsExist reports whether p exists, following symlinks: a dangling symlink
+// does not exist. Use LExists to check for the link itself.
 func (p Path) IsExist() bool {
 	_, err := p.Stat()
 	return err == nil
@@ -452,6 +1310,18 @@ func (p Path) DoesNotExist() bool {
 	return !p.IsExist()
 }
 
+// LExists reports whether p exists without following symlinks, so it is true
+// for a dangling symlink.
+func (p Path) LExists() bool {
+	_, err := p.LStat()
+	return err == nil
+}
+
+// IsBrokenSymlink reports whether p is a symlink whose target does not exist.
+func (p Path) IsBrokenSymlink() bool {
+	return p.IsSymlink() && !p.IsExist()
+}
+
 func (p Path) IsEqual(p2 Path) bool {
 	if p == p2 {
 		return true
@@ -470,31 +1340,70 @@ func (p Path) IsEqual(p2 Path) bool {
 }
 
 func (p Path) IsWritable() bool {
-	if !p.IsExist() {
-		return false
+	return p.IsExist() && p.AccessError() == nil
+}
+
+// AccessError explains why p can't be written, or returns nil if it can. An
+// existing regular file must be openable for writing and an existing directory
+// must allow creating entries in it. A missing path is writable if it can be
+// created: its deepest existing ancestor must be a writable directory, and the
+// error names both that ancestor and the first missing component. Underlying
+// errors are wrapped, so errors.Is(err, fs.ErrPermission) still works.
+func (p Path) AccessError() error {
+	fi, err := p.Stat()
+	if errors.Is(err, fs.ErrNotExist) {
+		return p.createError()
+	}
+	if err != nil {
+		return errz.E(err, "cannot access path").With("path", p)
 	}
 
-	if p.IsDir() {
-		tp := p.Join(".tmp_check_write")
-		f, err := os.OpenFile(string(tp), os.O_WRONLY|os.O_CREATE, 0o600)
+	switch {
+	case fi.IsDir():
+		if err := probeDir(p); err != nil {
+			return errz.E(err, "directory is not writable").With("path", p)
+		}
+	case fi.Mode().IsRegular():
+		f, err := os.OpenFile(string(p), os.O_WRONLY, 0)
 		if err != nil {
-			return false
+			if fi.Mode().Perm()&0o222 == 0 {
+				return errz.E(err, "file is read-only").With("path", p).With("mode", fi.Mode().Perm())
+			}
+			return errz.E(err, "file is not writable").With("path", p)
 		}
 		f.Close()
-		tp.Delete()
-		return true
+	default:
+		return errz.E("not a regular file or directory").With("path", p).With("type", fi.Mode().Type())
 	}
+	return nil
+}
 
-	if !p.IsRegular() {
-		return false
+// createError reports why the missing path p can't be created, or nil.
+func (p Path) createError() error {
+	existing, missing := p.splitExisting()
+	fi, err := existing.Stat()
+	if err != nil {
+		return errz.E(err, "cannot access ancestor").With("path", p).With("ancestor", existing)
 	}
+	if !fi.IsDir() {
+		return errz.E("ancestor is not a directory").With("path", p).With("ancestor", existing)
+	}
+	if err := probeDir(existing); err != nil {
+		return errz.E(err, "cannot create path: ancestor is not writable").
+			With("path", p).With("ancestor", existing).With("missing", missing)
+	}
+	return nil
+}
 
-	f, err := os.OpenFile(string(p), os.O_WRONLY, 0o600)
+// probeDir checks that entries can be created in dir by creating and removing
+// a uniquely named temporary file.
+func probeDir(dir Path) error {
+	f, err := os.CreateTemp(string(dir), ".tmp_check_write*")
 	if err != nil {
-		return false
+		return err
 	}
 	f.Close()
-	return true
+	return os.Remove(f.Name())
 }
 
 func (p Path) IsEmpty() bool {
@@ -502,19 +1411,27 @@ func (p Path) IsEmpty() bool {
 		return true
 	}
 
-	if p.IsDir() {
-		entries, err := p.ReadDir()
-		if err != nil {
-			return false
-		}
-		return len(entries) == 0
+	fi, err := p.Stat()
+	if err != nil {
+		return false
 	}
-
-	size, err := p.Size()
+	if !fi.IsDir() {
+		return fi.Size() == 0
+	}
+	// Read a single entry instead of listing the whole directory.
+	f, err := filesystem().Open(string(p))
 	if err != nil {
 		return false
 	}
-	return size == 0
+	defer f.Close()
+
+	d, ok := f.(fs.ReadDirFile)
+	if !ok {
+		entries, err := p.ReadDir()
+		return err == nil && len(entries) == 0
+	}
+	_, err = d.ReadDir(1)
+	return errors.Is(err, io.EOF)
 }
 
 func (p Path) HasPrefix(prefix string) bool {
@@ -552,6 +1469,114 @@ func (p Path) VolumeName() string {
 	return filepath.VolumeName(string(p))
 }
 
+// SplitVolume splits p into its volume name, such as C: or
+// \\server\share on Windows, and the rest of the path. On other systems
+// the volume is always empty and the whole path is returned as rest.
+func (p Path) SplitVolume() (volume, rest Path) {
+	v := p.VolumeName()
+	return Path(v), p[len(v):]
+}
+
+// SameVolume reports whether p and other reside on the same device or volume,
+// i.e. whether a rename between them can succeed without copying. A path that
+// does not exist yet is judged by its nearest existing ancestor. If no
+// ancestor can be inspected, the volume names of the absolute paths are
+// compared instead.
+func (p Path) SameVolume(other Path) (bool, error) {
+	v1, err1 := volumeOf(p.ExistingAncestor().String())
+	v2, err2 := volumeOf(other.ExistingAncestor().String())
+	if err1 == nil && err2 == nil {
+		return v1 == v2, nil
+	}
+
+	abs1, err := p.Abs()
+	if err != nil {
+		return false, err
+	}
+	abs2, err := other.Abs()
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(abs1.VolumeName(), abs2.VolumeName()), nil
+}
+
+// IsMountPoint reports whether p is the root of a mounted filesystem. On Unix
+// this is the case when p sits on a different device than its parent
+// directory, or when p is "/"; bind mounts of a directory from the same
+// filesystem are not detected. Symlinks are never mount points. On Windows it
+// is the case when p is the root of a volume, either a drive root such as
+// C:\ or a folder a volume is mounted on. It fails if p does not exist.
+func (p Path) IsMountPoint() (bool, error) {
+	abs, err := p.Abs()
+	if err != nil {
+		return false, err
+	}
+	ok, err := isMountPoint(string(abs))
+	if err != nil {
+		return false, errz.E(err, "check mount point").With("path", p)
+	}
+	return ok, nil
+}
+
+// ExistingAncestor returns the deepest path among p and its ancestors that
+// exists, following symlinks. p is made absolute first when possible, so the
+// result is absolute too. If nothing exists, not even the root, the root is
+// returned.
+func (p Path) ExistingAncestor() Path {
+	existing, _ := p.splitExisting()
+	return existing
+}
+
+// FirstMissingAncestor returns the shallowest path among p and its ancestors
+// that does not exist, i.e. the first directory that would have to be created
+// to create p, or p itself. It returns "" if p exists. Like ExistingAncestor,
+// the result is absolute when p can be made absolute.
+func (p Path) FirstMissingAncestor() Path {
+	_, missing := p.splitExisting()
+	return missing
+}
+
+// splitExisting walks up from p and returns the deepest existing path along
+// with its child on the way to p, which is the shallowest missing one.
+func (p Path) splitExisting() (existing, missing Path) {
+	v := p
+	if abs, err := p.Abs(); err == nil {
+		v = abs
+	}
+	for !v.Exists() {
+		parent := v.Dir()
+		if parent == v {
+			break
+		}
+		missing, v = v, parent
+	}
+	return v, missing
+}
+
+// ExpandEnv replaces $VAR and ${VAR} in p with the values of the environment
+// variables, using the empty string for undefined ones as os.ExpandEnv does.
+// A leading ~ is left untouched.
+func (p Path) ExpandEnv() Path {
+	return Path(os.ExpandEnv(string(p)))
+}
+
+// ExpandEnvStrict is like ExpandEnv but fails if p refers to an undefined
+// environment variable.
+func (p Path) ExpandEnvStrict() (Path, error) {
+	var missing []string
+	expanded := os.Expand(string(p), func(key string) string {
+		v, ok := os.LookupEnv(key)
+		if !ok {
+			missing = append(missing, key)
+		}
+		return v
+	})
+	if len(missing) > 0 {
+		return "", errz.E("undefined environment variable").With("names", missing)
+	}
+	return Path(expanded), nil
+}
+
 func (p Path) Clean() Path {
 	return Path(filepath.Clean(string(p)))
 }
@@ -560,8 +1585,80 @@ func (p Path) Normalize() Path {
 	return p.Clean()
 }
 
+// Key returns a deterministic string form of p for use as a map or cache key.
+// It cleans p lexically and converts separators to forward slashes, so
+// `a\b\..\c` and "a/c" yield "a/c" on Windows. On Windows and macOS, whose
+// default filesystems (NTFS, APFS) are case-insensitive, the result is also
+// case-folded with Unicode simple case folding: every rune is replaced by the
+// lowercase form of the smallest rune it folds with, so `Foo\Bar`, "foo/bar"
+// and "FOO/BAR" all yield "foo/bar" there, while on Linux they stay distinct.
+// Key is purely lexical: it does no I/O, resolves neither symlinks nor relative
+// paths, and does not account for case-sensitive volumes mounted on otherwise
+// case-insensitive systems.
+func (p Path) Key() string {
+	key := filepath.ToSlash(filepath.Clean(string(p)))
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		key = strings.Map(foldRune, key)
+	}
+	return key
+}
+
+// foldRune returns the lowercase form of the smallest rune in the simple case
+// folding orbit of r, which is the same for every rune that folds to r. Going
+// through the smallest rune keeps runes such as the Kelvin sign and long s,
+// whose lowercase forms differ from their orbit's, in the same key as "k" and
+// "s".
+func foldRune(r rune) rune {
+	folded := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		folded = min(folded, f)
+	}
+	return unicode.ToLower(folded)
+}
+
 func (p Path) Stat() (fs.FileInfo, error) {
-	return os.Stat(string(p))
+	return filesystem().Stat(string(p))
+}
+
+// Info holds the result of a single Stat so that several properties of a path
+// can be inspected without issuing a syscall for each one.
+type Info struct {
+	fs.FileInfo
+}
+
+func (i *Info) IsRegular() bool {
+	return i.Mode().IsRegular()
+}
+
+func (i *Info) IsDev() bool {
+	return i.Mode()&fs.ModeDevice != 0
+}
+
+// Info stats the path once and returns the result wrapped in an Info.
+func (p Path) Info() (*Info, error) {
+	fi, err := p.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return &Info{FileInfo: fi}, nil
+}
+
+// FileID identifies a file on the system: the device and inode on Unix, or the
+// volume serial number and file index on Windows. It is comparable and can be
+// used as a map key, e.g. to detect hard links or cycles during a walk.
+type FileID struct {
+	Device uint64
+	Index  uint64
+}
+
+// Identity returns the FileID of p, following symlinks.
+func (p Path) Identity() (FileID, error) {
+	return fileID(string(p))
+}
+
+// LStat is like Stat but describes a symlink itself instead of its target.
+func (p Path) LStat() (fs.FileInfo, error) {
+	return filesystem().Lstat(string(p))
 }
 
 func (p Path) Size() (int64, error) {
@@ -572,6 +1669,16 @@ func (p Path) Size() (int64, error) {
 	return fi.Size(), nil
 }
 
+// LSize is like Size but reports the size of a symlink itself, which is the
+// length of its target path, instead of the size of its target.
+func (p Path) LSize() (int64, error) {
+	fi, err := p.LStat()
+	if err != nil {
+		return 0, err
+	}
+	return fi.Size(), nil
+}
+
 func (p Path) SizeX() int64 {
 	size, _ := p.Size()
 	return size
@@ -586,10 +1693,8 @@ func (p Path) HasQuery() bool {
 }
 
 func (p Path) WithoutQuery() Path {
-	if !p.HasQuery() {
-		return p
-	}
-	return Path(strings.Split(string(p), "?")[0])
+	path, _, _ := strings.Cut(string(p), "?")
+	return Path(path)
 }
 
 func (p Path) WithQuery(q string) Path {
@@ -600,10 +1705,18 @@ func (p Path) WithQuery(q string) Path {
 }
 
 func (p Path) Query() string {
-	if !p.HasQuery() {
-		return ""
-	}
-	return strings.Split(string(p), "?")[1]
+	_, query, _ := strings.Cut(string(p), "?")
+	return query
+}
+
+// ParseURL splits p, read as a URL path like "/a/b?x=1#frag", into its path,
+// decoded query and fragment. Missing parts are returned empty, and malformed
+// query pairs are skipped.
+func (p Path) ParseURL() (path Path, query url.Values, fragment string) {
+	rest, fragment, _ := strings.Cut(string(p), "#")
+	rest, rawQuery, _ := strings.Cut(rest, "?")
+	query, _ = url.ParseQuery(rawQuery)
+	return Path(rest), query, fragment
 }
 
 func (p Path) QuerySet(k string, v any) Path {
@@ -637,14 +1750,111 @@ func (p Path) QueryHas(k string) bool {
 	return false
 }
 
+// EscapePath percent-encodes each slash-separated segment of the path part of
+// p with url.PathEscape, leaving the separators and any query untouched, so
+// the result can be used directly as the path of a URL.
+func (p Path) EscapePath() string {
+	segs := strings.Split(filepath.ToSlash(string(p.WithoutQuery())), "/")
+	for i, seg := range segs {
+		segs[i] = url.PathEscape(seg)
+	}
+
+	escaped := strings.Join(segs, "/")
+	if p.HasQuery() {
+		escaped += "?" + p.Query()
+	}
+	return escaped
+}
+
+// UnescapePath decodes the percent-encoding of each segment of the path part
+// of p, leaving any query untouched. Segments that are not validly encoded are
+// kept as they are.
+func (p Path) UnescapePath() Path {
+	segs := strings.Split(string(p.WithoutQuery()), "/")
+	for i, seg := range segs {
+		if s, err := url.PathUnescape(seg); err == nil {
+			segs[i] = s
+		}
+	}
+
+	unescaped := Path(strings.Join(segs, "/"))
+	if p.HasQuery() {
+		unescaped = unescaped.WithQuery(p.Query())
+	}
+	return unescaped
+}
+
+// RelURL returns a relative URL reference that resolves to p when used in a
+// document at base, as an href would. Only forward slashes are treated as
+// separators, on every platform. Queries are ignored when computing the
+// result and the query of p is kept. As in URL resolution, the last segment of
+// base is dropped unless base ends with a slash, and a trailing slash on p is
+// preserved. If exactly one of p and base is absolute, p is returned as is.
+func (p Path) RelURL(base Path) Path {
+	target, query := string(p.WithoutQuery()), p.Query()
+	from := string(base.WithoutQuery())
+	if strings.HasPrefix(target, "/") != strings.HasPrefix(from, "/") {
+		return p
+	}
+
+	isDir := strings.HasSuffix(target, "/") || strings.HasSuffix(target, "/.") || target == "." || target == ""
+	if i := strings.LastIndex(from, "/"); i >= 0 {
+		from = from[:i+1]
+	} else {
+		from = ""
+	}
+	targetSegs, fromSegs := urlSegments(target), urlSegments(from)
+
+	common := 0
+	for common < len(targetSegs) && common < len(fromSegs) && targetSegs[common] == fromSegs[common] {
+		common++
+	}
+	// A file target that is an ancestor of the base directory must still name
+	// its last segment, or the reference would resolve to the directory.
+	if !isDir && common == len(targetSegs) && common > 0 {
+		common--
+	}
+
+	rel := strings.Repeat("../", len(fromSegs)-common) + strings.Join(targetSegs[common:], "/")
+	if isDir && common < len(targetSegs) {
+		rel += "/"
+	}
+	// A colon in the first segment would be read as a URL scheme.
+	if first, _, _ := strings.Cut(rel, "/"); rel == "" || strings.Contains(first, ":") {
+		rel = "./" + rel
+	}
+	if query != "" {
+		rel += "?" + query
+	}
+	return Path(rel)
+}
+
+// urlSegments returns the cleaned slash-separated segments of p, ignoring
+// whether it is absolute.
+func urlSegments(p string) []string {
+	p = strings.TrimPrefix(path.Clean("/"+p), "/")
+	if p == "" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
 func (p Path) hashFile(h hash.Hash) string {
-	f, err := p.Open()
+	sum, _ := p.digest(h)
+	return sum
+}
+
+func (p Path) digest(h hash.Hash) (string, error) {
+	f, err := p.OpenHandle()
 	if err != nil {
-		return ""
+		return "", err
 	}
-	f.WriteTo(h)
-	f.Close()
-	return hex.EncodeToString(h.Sum(nil))
+	defer f.Close()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
 func (p Path) MD5() string {
@@ -664,6 +1874,12 @@ type Usage struct {
 	Used        uint64
 	Free        uint64
 	UsedPercent float64
+
+	// Inode counts are zero on filesystems that don't report them (e.g. on Windows).
+	InodesTotal       uint64
+	InodesUsed        uint64
+	InodesFree        uint64
+	InodesUsedPercent float64
 }
 
 func (p Path) Times() (created, modified, accessed time.Time) {
@@ -681,6 +1897,11 @@ func (p Path) Usage() (u Usage, err error) {
 		Used:        s.Used,
 		Free:        s.Free,
 		UsedPercent: s.UsedPercent,
+
+		InodesTotal:       s.InodesTotal,
+		InodesUsed:        s.InodesUsed,
+		InodesFree:        s.InodesFree,
+		InodesUsedPercent: s.InodesUsedPercent,
 	}, nil
 }
 
@@ -1,16 +1,27 @@
 package ppath
 
 import (
+	"bytes"
 	"crypto/md5"
 	"crypto/sha1"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
 	"io/fs"
 	"log"
+	"maps"
+	"math"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 var testContent = []byte("test content")
@@ -29,6 +40,37 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestNewExpand(t *testing.T) {
+	t.Setenv("PPATH_TEST_BASE", "base")
+	p := NewExpand("$PPATH_TEST_BASE", "${PPATH_TEST_BASE}.d", "file")
+	expected := filepath.Join("base", "base.d", "file")
+	if p.String() != expected {
+		t.Errorf("expected %s, got %s", expected, p.String())
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("PPATH_TEST_HOME", "/home/test")
+	os.Unsetenv("PPATH_TEST_UNDEFINED")
+
+	p := Path("${PPATH_TEST_HOME}/app/$PPATH_TEST_UNDEFINED")
+	if expected := Path("/home/test/app/"); p.ExpandEnv() != expected {
+		t.Errorf("expected %s, got %s", expected, p.ExpandEnv())
+	}
+	if _, err := p.ExpandEnvStrict(); err == nil {
+		t.Errorf("expected error for undefined variable, got nil")
+	}
+
+	p = Path("~/$PPATH_TEST_HOME")
+	expanded, err := p.ExpandEnvStrict()
+	if err != nil {
+		t.Fatalf("ExpandEnvStrict: %v", err)
+	}
+	if expected := Path("~//home/test"); expanded != expected {
+		t.Errorf("expected %s, got %s", expected, expanded)
+	}
+}
+
 func TestJoin(t *testing.T) {
 	p := New("a", "b")
 	p = p.Join("c", "d")
@@ -62,6 +104,95 @@ func TestNthParent(t *testing.T) {
 	}
 }
 
+func TestNthParentOK(t *testing.T) {
+	tests := []struct {
+		path     Path
+		n        int
+		expected Path
+		ok       bool
+	}{
+		{New("a", "b", "c"), 1, New("a", "b"), true},
+		{New("a", "b", "c"), 3, ".", true},
+		{New("a", "b", "c"), 4, New("a", "b", "c"), false},
+		{New("a", "b", "..", "c"), 2, ".", true},
+		{New("a", "b", "..", "c"), 3, New("a", "c"), false},
+		{Path(filepath.Separator) + New("a", "b"), 2, Path(filepath.Separator), true},
+		{Path(filepath.Separator) + New("a", "b"), 3, Path(filepath.Separator) + New("a", "b"), false},
+		{New("a"), -1, New("a"), false},
+	}
+
+	for _, test := range tests {
+		result, ok := test.path.NthParentOK(test.n)
+		if result != test.expected || ok != test.ok {
+			t.Errorf("expected (%s, %v), got (%s, %v) for path %s and n %d", test.expected, test.ok, result, ok, test.path, test.n)
+		}
+	}
+}
+
+func TestRoot(t *testing.T) {
+	sep := Path(filepath.Separator)
+	if p := sep + New("a", "b"); p.Root() != sep {
+		t.Errorf("expected %s, got %s", sep, p.Root())
+	}
+	if p := New("a", "b"); p.Root() != "" {
+		t.Errorf("expected empty root, got %s", p.Root())
+	}
+	if runtime.GOOS == "windows" {
+		if p := Path(`C:\a\b`); p.Root() != `C:\` {
+			t.Errorf("expected C:\\, got %s", p.Root())
+		}
+		if p := Path(`\\server\share\a`); p.Root() != `\\server\share\` {
+			t.Errorf("expected \\\\server\\share\\, got %s", p.Root())
+		}
+	}
+}
+
+func TestTrimPrefix(t *testing.T) {
+	sep := Path(filepath.Separator)
+	tests := []struct {
+		path     Path
+		prefix   Path
+		expected Path
+	}{
+		{New("a", "b", "c", "d"), New("a", "b"), New("c", "d")},
+		{New("a", "bc", "d"), New("a", "b"), New("a", "bc", "d")},
+		{New("a", "b"), New("a", "b"), "."},
+		{New("a", "b"), New("a", "b", "c"), New("a", "b")},
+		{sep + New("a", "b", "c"), sep + New("a"), New("b", "c")},
+		{sep + New("a", "b", "c"), New("a"), sep + New("a", "b", "c")},
+		{New("a", "b", "c"), "a/./b/", "c"},
+	}
+
+	for _, test := range tests {
+		result := test.path.TrimPrefix(test.prefix)
+		if result != test.expected {
+			t.Errorf("expected %s, got %s for path %s and prefix %s", test.expected, result, test.path, test.prefix)
+		}
+	}
+}
+
+func TestTrimSuffix(t *testing.T) {
+	sep := Path(filepath.Separator)
+	tests := []struct {
+		path     Path
+		suffix   Path
+		expected Path
+	}{
+		{New("a", "b", "c", "d"), New("c", "d"), New("a", "b")},
+		{New("a", "bc", "d"), New("c", "d"), New("a", "bc", "d")},
+		{New("a", "b"), New("a", "b"), "."},
+		{sep + New("a", "b"), New("a", "b"), sep},
+		{sep + New("a", "b"), sep + New("a", "b"), sep + New("a", "b")},
+	}
+
+	for _, test := range tests {
+		result := test.path.TrimSuffix(test.suffix)
+		if result != test.expected {
+			t.Errorf("expected %s, got %s for path %s and suffix %s", test.expected, result, test.path, test.suffix)
+		}
+	}
+}
+
 func TestExt(t *testing.T) {
 	p := New("a", "b", "c.txt")
 	expected := ".txt"
@@ -79,6 +210,85 @@ func TestSplit(t *testing.T) {
 	}
 }
 
+func TestSegmentsSeq(t *testing.T) {
+	tests := []struct {
+		path     Path
+		expected []string
+	}{
+		{"/users/42/posts", []string{"users", "42", "posts"}},
+		{"users/42/posts", []string{"users", "42", "posts"}},
+		{"/users//42/./posts/", []string{"users", "42", "posts"}},
+		{"/users/42/../7", []string{"users", "7"}},
+		{"../../shared", []string{"..", "..", "shared"}},
+		{"/", nil},
+		{".", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := slices.Collect(tt.path.SegmentsSeq()); !slices.Equal(got, tt.expected) {
+			t.Errorf("SegmentsSeq(%q): expected %q, got %q", tt.path, tt.expected, got)
+		}
+	}
+
+	var seen []string
+	for seg := range Path("/users/42/posts").SegmentsSeq() {
+		seen = append(seen, seg)
+		if seg == "42" {
+			break
+		}
+	}
+	if !slices.Equal(seen, []string{"users", "42"}) {
+		t.Errorf("expected early exit after 42, got %q", seen)
+	}
+}
+
+func TestKey(t *testing.T) {
+	folds := runtime.GOOS == "windows" || runtime.GOOS == "darwin"
+	tests := []struct {
+		path     Path
+		expected string
+	}{
+		{"a/b/../c/", "a/c"},
+		{"./a//b", "a/b"},
+		{"", "."},
+		{"/Users/Foo/Bar.TXT", "/Users/Foo/Bar.TXT"},
+	}
+	if runtime.GOOS == "windows" {
+		tests = append(tests, struct {
+			path     Path
+			expected string
+		}{`C:\Users\Foo\..\Bar`, "C:/Users/Bar"})
+	}
+	for _, tt := range tests {
+		expected := tt.expected
+		if folds {
+			expected = strings.ToLower(expected)
+		}
+		if got := tt.path.Key(); got != expected {
+			t.Errorf("Key(%q): expected %q, got %q", tt.path, expected, got)
+		}
+	}
+
+	same := Path("Foo/Bar").Key() == Path("foo/bar").Key()
+	if same != folds {
+		t.Errorf("expected case collision %v on %s, got %v", folds, runtime.GOOS, same)
+	}
+
+	// Runes that fold together share a lowercase key rune, even where
+	// lowercasing alone would keep them apart.
+	for _, group := range []string{"aA", "kK\u212a", "σςΣ", "sSſ"} {
+		runes := []rune(group)
+		for _, r := range runes {
+			if foldRune(r) != runes[0] {
+				t.Errorf("expected %q to fold to %q, got %q", r, runes[0], foldRune(r))
+			}
+		}
+	}
+	if foldRune('a') == foldRune('b') {
+		t.Errorf("expected a and b to fold apart")
+	}
+}
+
 func TestRel(t *testing.T) {
 	p := New("a", "b", "c", "d")
 	r := New("a", "b")
@@ -89,6 +299,62 @@ func TestRel(t *testing.T) {
 	}
 }
 
+func TestRelURL(t *testing.T) {
+	tests := []struct {
+		p, base  Path
+		expected Path
+	}{
+		{"/docs/guide/intro.html", "/docs/index.html", "guide/intro.html"},
+		{"/docs/guide/intro.html", "/docs/", "guide/intro.html"},
+		{"/docs/index.html", "/docs/guide/intro.html", "../index.html"},
+		{"/img/logo.png", "/docs/guide/intro.html", "../../img/logo.png"},
+		{"/docs/index.html", "/docs/index.html", "index.html"},
+		{"/docs/", "/docs/index.html", "./"},
+		{"/docs/", "/docs/guide/intro.html", "../"},
+		{"/docs", "/docs/index.html", "../docs"},
+		{"/docs/api/", "/docs/index.html", "api/"},
+		{"/search?q=go", "/docs/index.html?lang=en", "../search?q=go"},
+		{"/a:b", "/index.html", "./a:b"},
+		{"a/b/c", "a/x", "b/c"},
+		{"/abs/file", "rel/file", "/abs/file"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.p.RelURL(tt.base); got != tt.expected {
+			t.Errorf("RelURL(%s, %s): expected %s, got %s", tt.p, tt.base, tt.expected, got)
+		}
+	}
+}
+
+func TestEscapePath(t *testing.T) {
+	tests := []struct {
+		p       Path
+		escaped string
+	}{
+		{"/files/my report.pdf", "/files/my%20report.pdf"},
+		{"/a b/c#d/100%", "/a%20b/c%23d/100%25"},
+		{"/plain/path", "/plain/path"},
+		{"/my docs/list", "/my%20docs/list"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.p.EscapePath(); got != tt.escaped {
+			t.Errorf("EscapePath(%s): expected %s, got %s", tt.p, tt.escaped, got)
+		}
+		if got := Path(tt.escaped).UnescapePath(); got != tt.p {
+			t.Errorf("UnescapePath(%s): expected %s, got %s", tt.escaped, tt.p, got)
+		}
+	}
+
+	withQuery := New("/my docs/list").QuerySet("page", 2)
+	if expected, got := "/my%20docs/list?page=2", withQuery.EscapePath(); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+	if expected, got := Path("/bad%zz/ok%20"), Path("/bad%zz/ok%2520").UnescapePath(); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
+
 func TestAbs(t *testing.T) {
 	p := New(".")
 	abs, err := p.Abs()
@@ -101,6 +367,33 @@ func TestAbs(t *testing.T) {
 	}
 }
 
+func TestAbsFrom(t *testing.T) {
+	base := New(string(filepath.Separator), "etc", "app")
+	rel := New("conf", "..", "data.json")
+	abs, err := rel.AbsFrom(base)
+	if err != nil {
+		t.Fatalf("AbsFrom: %v", err)
+	}
+	expected := base.Join("data.json")
+	if abs != expected {
+		t.Errorf("expected %s, got %s", expected, abs)
+	}
+
+	abs, err = base.AbsFrom(New("ignored"))
+	if err != nil || abs != base {
+		t.Errorf("expected %s unchanged, got %s, error: %v", base, abs, err)
+	}
+
+	abs, err = rel.AbsFrom(New("relbase"))
+	if err != nil {
+		t.Fatalf("AbsFrom: %v", err)
+	}
+	expected = WD().Join("relbase", "data.json")
+	if abs != expected {
+		t.Errorf("expected %s, got %s", expected, abs)
+	}
+}
+
 func TestDelete(t *testing.T) {
 	p := New("testdir")
 	os.Mkdir(p.String(), 0o755)
@@ -168,6 +461,51 @@ func TestIsSymlink(t *testing.T) {
 	os.Remove(symlink.String())
 }
 
+func TestLStat(t *testing.T) {
+	tempDir := New(t.TempDir())
+	file := tempDir.Join("file.txt")
+	if err := file.WriteFile(testContent); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	fileLink := tempDir.Join("filelink")
+	if err := os.Symlink(file.String(), fileLink.String()); err != nil {
+		t.Fatalf("os.Symlink: %v", err)
+	}
+	dirLink := tempDir.Join("dirlink")
+	if err := os.Symlink(tempDir.String(), dirLink.String()); err != nil {
+		t.Fatalf("os.Symlink: %v", err)
+	}
+
+	fi, err := fileLink.LStat()
+	if err != nil {
+		t.Fatalf("LStat: %v", err)
+	}
+	if fi.Mode()&fs.ModeSymlink == 0 {
+		t.Errorf("expected LStat to describe the symlink")
+	}
+
+	size, err := fileLink.LSize()
+	if err != nil {
+		t.Fatalf("LSize: %v", err)
+	}
+	if size != int64(len(file.String())) {
+		t.Errorf("expected link size %d, got %d", len(file.String()), size)
+	}
+	if size, _ := fileLink.Size(); size != int64(len(testContent)) {
+		t.Errorf("expected target size %d, got %d", len(testContent), size)
+	}
+
+	if dirLink.LIsDir() {
+		t.Errorf("expected LIsDir to be false for a symlink")
+	}
+	if !dirLink.IsDir() {
+		t.Errorf("expected IsDir to follow the symlink")
+	}
+	if !tempDir.LIsDir() {
+		t.Errorf("expected LIsDir to be true for a directory")
+	}
+}
+
 func TestIsDev(t *testing.T) {
 	// This test is platform dependent and might not work on all systems.
 	// It is generally difficult to create a device file in a cross-platform manner.
@@ -194,6 +532,45 @@ func TestIsExist(t *testing.T) {
 	}
 }
 
+func TestLExists(t *testing.T) {
+	tempDir := New(t.TempDir())
+	file := tempDir.Join("file.txt")
+	if err := file.WriteFile(testContent); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	link := tempDir.Join("link")
+	if err := os.Symlink(file.String(), link.String()); err != nil {
+		t.Fatalf("os.Symlink: %v", err)
+	}
+	dangling := tempDir.Join("dangling")
+	if err := os.Symlink(tempDir.Join("missing").String(), dangling.String()); err != nil {
+		t.Fatalf("os.Symlink: %v", err)
+	}
+
+	tests := []struct {
+		path          Path
+		exists        bool
+		lexists       bool
+		brokenSymlink bool
+	}{
+		{file, true, true, false},
+		{link, true, true, false},
+		{dangling, false, true, true},
+		{tempDir.Join("missing"), false, false, false},
+	}
+	for _, test := range tests {
+		if test.path.IsExist() != test.exists {
+			t.Errorf("expected IsExist %v for %s", test.exists, test.path)
+		}
+		if test.path.LExists() != test.lexists {
+			t.Errorf("expected LExists %v for %s", test.lexists, test.path)
+		}
+		if test.path.IsBrokenSymlink() != test.brokenSymlink {
+			t.Errorf("expected IsBrokenSymlink %v for %s", test.brokenSymlink, test.path)
+		}
+	}
+}
+
 func TestMatch(t *testing.T) {
 	p := New("testfile.txt")
 	if !p.Match("*.txt") {
@@ -212,6 +589,170 @@ func TestVolumeName(t *testing.T) {
 	}
 }
 
+func TestSplitVolume(t *testing.T) {
+	type volumeTest struct {
+		path   Path
+		volume Path
+		rest   Path
+	}
+	tests := []volumeTest{
+		{"/path/to/file", "", "/path/to/file"},
+		{"relative/file", "", "relative/file"},
+	}
+	if runtime.GOOS == "windows" {
+		tests = []volumeTest{
+			{`C:\path\to\file`, "C:", `\path\to\file`},
+			{`\\server\share\dir\file`, `\\server\share`, `\dir\file`},
+			{`relative\file`, "", `relative\file`},
+		}
+	}
+
+	for _, test := range tests {
+		volume, rest := test.path.SplitVolume()
+		if volume != test.volume || rest != test.rest {
+			t.Errorf("expected (%s, %s), got (%s, %s) for path %s", test.volume, test.rest, volume, rest, test.path)
+		}
+	}
+}
+
+func TestSameVolume(t *testing.T) {
+	tempDir := New(t.TempDir())
+	file := tempDir.Join("file.txt")
+	if err := file.WriteFile(testContent); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	same, err := file.SameVolume(tempDir.Join("not", "yet", "created"))
+	if err != nil {
+		t.Fatalf("SameVolume: %v", err)
+	}
+	if !same {
+		t.Errorf("expected paths in the same directory to share a volume")
+	}
+
+	if runtime.GOOS == "linux" && New("/proc").IsDir() {
+		same, err = file.SameVolume("/proc")
+		if err != nil {
+			t.Fatalf("SameVolume: %v", err)
+		}
+		if same {
+			t.Errorf("expected /proc to be on a different volume")
+		}
+	}
+}
+
+func TestIsMountPoint(t *testing.T) {
+	tempDir := New(t.TempDir())
+	file := tempDir.Join("file.txt")
+	if err := file.WriteFile(testContent); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	for _, p := range []Path{tempDir, file} {
+		if ok, err := p.IsMountPoint(); err != nil || ok {
+			t.Errorf("expected %s not to be a mount point, got %v, error: %v", p, ok, err)
+		}
+	}
+
+	root := tempDir.Root()
+	if ok, err := root.IsMountPoint(); err != nil || !ok {
+		t.Errorf("expected %s to be a mount point, got %v, error: %v", root, ok, err)
+	}
+
+	if runtime.GOOS == "linux" && New("/proc").IsDir() {
+		if ok, err := New("/proc").IsMountPoint(); err != nil || !ok {
+			t.Errorf("expected /proc to be a mount point, got %v, error: %v", ok, err)
+		}
+	}
+
+	if _, err := tempDir.Join("missing").IsMountPoint(); err == nil {
+		t.Errorf("expected error for a missing path, got nil")
+	}
+}
+
+func TestRoots(t *testing.T) {
+	roots, err := Roots()
+	if err != nil {
+		t.Fatalf("Roots: %v", err)
+	}
+	if len(roots) == 0 {
+		t.Fatalf("expected at least one root, got none")
+	}
+	for _, root := range roots {
+		if !root.IsDir() {
+			t.Errorf("expected %s to be a directory", root)
+		}
+		if ok, err := root.IsMountPoint(); err != nil || !ok {
+			t.Errorf("expected %s to be a mount point, got %v, error: %v", root, ok, err)
+		}
+	}
+	if runtime.GOOS != "windows" && (len(roots) != 1 || roots[0] != "/") {
+		t.Errorf("expected [/], got %v", roots)
+	}
+}
+
+func TestExistingAncestor(t *testing.T) {
+	tempDir := New(t.TempDir())
+	if err := tempDir.Join("a", "b").MkdirIfNotExist(); err != nil {
+		t.Fatalf("MkdirIfNotExist: %v", err)
+	}
+
+	tests := []struct {
+		path     Path
+		existing Path
+		missing  Path
+	}{
+		{tempDir.Join("a", "b", "c", "d"), tempDir.Join("a", "b"), tempDir.Join("a", "b", "c")},
+		{tempDir.Join("a", "b", "c"), tempDir.Join("a", "b"), tempDir.Join("a", "b", "c")},
+		{tempDir.Join("a", "b"), tempDir.Join("a", "b"), ""},
+		{tempDir.Join("x"), tempDir, tempDir.Join("x")},
+	}
+
+	for _, tt := range tests {
+		if got := tt.path.ExistingAncestor(); got != tt.existing {
+			t.Errorf("ExistingAncestor(%s): expected %s, got %s", tt.path, tt.existing, got)
+		}
+		if got := tt.path.FirstMissingAncestor(); got != tt.missing {
+			t.Errorf("FirstMissingAncestor(%s): expected %s, got %s", tt.path, tt.missing, got)
+		}
+	}
+}
+
+func TestIdentity(t *testing.T) {
+	tempDir := New(t.TempDir())
+	file := tempDir.Join("file.txt")
+	other := tempDir.Join("other.txt")
+	hardlink := tempDir.Join("hardlink.txt")
+	if err := file.WriteFile(testContent); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := other.WriteFile(testContent); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.Link(file.String(), hardlink.String()); err != nil {
+		t.Fatalf("os.Link: %v", err)
+	}
+
+	seen := make(map[FileID]Path)
+	for _, p := range []Path{file, other, hardlink} {
+		id, err := p.Identity()
+		if err != nil {
+			t.Fatalf("Identity: %v", err)
+		}
+		if prev, ok := seen[id]; ok && p != hardlink {
+			t.Errorf("unexpected identity collision between %s and %s", prev, p)
+		}
+		seen[id] = p
+	}
+	if len(seen) != 2 {
+		t.Errorf("expected hard link to share the identity of its target, got %d identities", len(seen))
+	}
+
+	if _, err := tempDir.Join("missing").Identity(); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
 func TestSize(t *testing.T) {
 	p := New("testfile.txt")
 	os.WriteFile(p.String(), []byte("test"), 0o644)
@@ -279,6 +820,302 @@ func TestReadFile(t *testing.T) {
 	os.Remove(p.String())
 }
 
+func TestReadInto(t *testing.T) {
+	p := New(t.TempDir()).Join("file.txt")
+	if err := p.WriteFile(testContent); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	buf := make([]byte, 4)
+	n, err := p.ReadInto(buf)
+	if err != nil || n != 4 || string(buf) != string(testContent[:4]) {
+		t.Errorf("expected (4, %q), got (%d, %q), error: %v", testContent[:4], n, buf[:n], err)
+	}
+
+	buf = make([]byte, 64)
+	n, err = p.ReadInto(buf)
+	if err != nil || n != len(testContent) || string(buf[:n]) != string(testContent) {
+		t.Errorf("expected (%d, %q), got (%d, %q), error: %v", len(testContent), testContent, n, buf[:n], err)
+	}
+}
+
+func TestTeeWriter(t *testing.T) {
+	p := New(t.TempDir(), "logs", "run.log")
+	if err := p.Dir().MkdirIfNotExist(); err != nil {
+		t.Fatalf("MkdirIfNotExist: %v", err)
+	}
+	if err := p.WriteFile([]byte("earlier\n")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	var out bytes.Buffer
+	w, err := p.TeeWriter(&out)
+	if err != nil {
+		t.Fatalf("TeeWriter: %v", err)
+	}
+	if _, err := io.WriteString(w, "hello\n"); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if out.String() != "hello\n" {
+		t.Errorf("expected hello in writer, got %q", out.String())
+	}
+	if content, _ := p.ReadFile(); string(content) != "earlier\nhello\n" {
+		t.Errorf("expected appended content, got %q", content)
+	}
+
+	created := New(t.TempDir(), "new", "dir", "out.log")
+	w, err = created.TeeWriter(io.Discard)
+	if err != nil {
+		t.Fatalf("TeeWriter: %v", err)
+	}
+	w.Close()
+	if !created.IsRegular() {
+		t.Errorf("expected file and parent directories to be created")
+	}
+}
+
+func TestReadInt(t *testing.T) {
+	p := New(t.TempDir(), "run", "app.pid")
+
+	if err := p.WriteInt(4242); err != nil {
+		t.Fatalf("WriteInt: %v", err)
+	}
+	if content, _ := p.ReadFile(); string(content) != "4242\n" {
+		t.Errorf("expected 4242 and a newline, got %q", content)
+	}
+	if n, err := p.ReadInt(); err != nil || n != 4242 {
+		t.Errorf("expected 4242, got %d, error: %v", n, err)
+	}
+
+	if err := p.WriteInt(-7); err != nil {
+		t.Fatalf("WriteInt: %v", err)
+	}
+	if n, err := p.ReadInt(); err != nil || n != -7 {
+		t.Errorf("expected -7, got %d, error: %v", n, err)
+	}
+	if entries, _ := p.Dir().ReadDir(); len(entries) != 1 {
+		t.Errorf("expected no temporary files to be left, got %d entries", len(entries))
+	}
+
+	if err := p.WriteFile([]byte("  v1.2.3\n\n")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if s, err := p.ReadString(); err != nil || s != "v1.2.3" {
+		t.Errorf("expected v1.2.3, got %q, error: %v", s, err)
+	}
+	_, err := p.ReadInt()
+	if err == nil {
+		t.Fatalf("expected error for non-numeric content, got nil")
+	}
+	if !strings.Contains(err.Error(), `"v1.2.3"`) {
+		t.Errorf("expected error to quote the content, got %v", err)
+	}
+
+	if _, err := p.Dir().Join("missing").ReadInt(); err == nil {
+		t.Errorf("expected error for a missing file, got nil")
+	}
+}
+
+func TestReadFileTimeout(t *testing.T) {
+	p := New(t.TempDir()).Join("health.txt")
+	if err := p.WriteFile(testContent); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	content, err := p.ReadFileTimeout(time.Second)
+	if err != nil {
+		t.Fatalf("ReadFileTimeout: %v", err)
+	}
+	if string(content) != string(testContent) {
+		t.Errorf("expected %s, got %s", testContent, content)
+	}
+
+	if _, err := p.Dir().Join("missing.txt").ReadFileTimeout(time.Second); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist, got %v", err)
+	}
+}
+
+func TestReadFileLimit(t *testing.T) {
+	p := New(t.TempDir()).Join("file.txt")
+	if err := p.WriteFile([]byte("0123456789")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	for _, limit := range []int64{10, 100, math.MaxInt64} {
+		content, err := p.ReadFileLimit(limit)
+		if err != nil {
+			t.Fatalf("ReadFileLimit(%d): %v", limit, err)
+		}
+		if string(content) != "0123456789" {
+			t.Errorf("expected 0123456789, got %s", content)
+		}
+	}
+
+	_, err := p.ReadFileLimit(9)
+	if !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("expected ErrTooLarge, got %v", err)
+	}
+	if msg := err.Error(); !strings.Contains(msg, "10") || !strings.Contains(msg, "9") {
+		t.Errorf("expected error to include size and limit, got %s", msg)
+	}
+
+	if _, err := p.Dir().ReadFileLimit(100); err == nil {
+		t.Errorf("expected error for a directory, got nil")
+	}
+	if _, err := p.ReadFileLimit(-1); err == nil {
+		t.Errorf("expected error for a negative limit, got nil")
+	}
+
+	// procfs files report a size of zero, so only the limit enforced while
+	// reading can catch them.
+	if runtime.GOOS == "linux" {
+		if _, err := New("/proc/self/status").ReadFileLimit(10); !errors.Is(err, ErrTooLarge) {
+			t.Errorf("expected ErrTooLarge while reading, got %v", err)
+		}
+	}
+}
+
+func TestReadFull(t *testing.T) {
+	p := New(t.TempDir()).Join("file.txt")
+	if err := p.WriteFile(testContent); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	buf := make([]byte, 4)
+	if err := p.ReadFull(buf); err != nil || string(buf) != string(testContent[:4]) {
+		t.Errorf("expected %q, got %q, error: %v", testContent[:4], buf, err)
+	}
+
+	if err := p.ReadFull(make([]byte, 64)); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected ErrUnexpectedEOF, got %v", err)
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	p := New(t.TempDir()).Join("logs", "wal")
+	if err := p.Allocate(1 << 20); err != nil {
+		t.Fatalf("Allocate: %v", err)
+	}
+	if size, _ := p.Size(); size != 1<<20 {
+		t.Errorf("expected size %d, got %d", 1<<20, size)
+	}
+
+	if err := p.WriteAt(10, []byte("data")); err != nil {
+		t.Fatalf("WriteAt: %v", err)
+	}
+	if err := p.Allocate(100); err != nil {
+		t.Fatalf("Allocate: %v", err)
+	}
+	if size, _ := p.Size(); size != 1<<20 {
+		t.Errorf("expected a smaller size not to truncate, got %d", size)
+	}
+	if err := p.Allocate(2 << 20); err != nil {
+		t.Fatalf("Allocate: %v", err)
+	}
+	content, err := p.ReadFile()
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if len(content) != 2<<20 || string(content[10:14]) != "data" {
+		t.Errorf("expected %d bytes with data kept, got %d", 2<<20, len(content))
+	}
+
+	if err := p.Allocate(-1); err == nil {
+		t.Errorf("expected error for a negative size, got nil")
+	}
+	if err := p.Dir().Allocate(10); err == nil {
+		t.Errorf("expected error for a directory, got nil")
+	}
+}
+
+func TestWriteAt(t *testing.T) {
+	tempDir := New(t.TempDir())
+	p := tempDir.Join("file.bin")
+	if err := p.WriteFile([]byte("0123456789")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	if err := p.WriteAt(2, []byte("ab")); err != nil {
+		t.Fatalf("WriteAt: %v", err)
+	}
+	if content, _ := p.ReadFile(); string(content) != "01ab456789" {
+		t.Errorf("expected 01ab456789, got %q", content)
+	}
+
+	if err := p.WriteAt(12, []byte("z")); err != nil {
+		t.Fatalf("WriteAt: %v", err)
+	}
+	if content, _ := p.ReadFile(); string(content) != "01ab456789\x00\x00z" {
+		t.Errorf("expected zero-filled gap, got %q", content)
+	}
+
+	created := tempDir.Join("sub", "new.bin")
+	if err := created.WriteAt(3, []byte("x")); err != nil {
+		t.Fatalf("WriteAt: %v", err)
+	}
+	if content, _ := created.ReadFile(); string(content) != "\x00\x00\x00x" {
+		t.Errorf("expected zero-filled new file, got %q", content)
+	}
+
+	if err := tempDir.WriteAt(0, []byte("x")); err == nil {
+		t.Errorf("expected error for a directory, got nil")
+	}
+	if err := p.WriteAt(-1, []byte("x")); err == nil {
+		t.Errorf("expected error for a negative offset, got nil")
+	}
+}
+
+func TestEnsureEmptyDir(t *testing.T) {
+	tempDir := New(t.TempDir())
+
+	created := tempDir.Join("new", "out")
+	if err := created.EnsureEmptyDir(); err != nil {
+		t.Fatalf("EnsureEmptyDir: %v", err)
+	}
+	if !created.IsDir() || !created.IsEmpty() {
+		t.Errorf("expected an empty directory to be created")
+	}
+
+	out := tempDir.Join("out")
+	outside := tempDir.Join("outside.txt")
+	for _, p := range []Path{out.Join("stale.txt"), out.Join("sub", "stale.txt"), outside} {
+		if err := p.WriteFile(testContent); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	if err := os.Symlink(outside.String(), out.Join("link").String()); err != nil {
+		t.Fatalf("os.Symlink: %v", err)
+	}
+	if err := os.Chmod(out.String(), 0o750); err != nil {
+		t.Fatalf("Chmod: %v", err)
+	}
+
+	if err := out.EnsureEmptyDir(); err != nil {
+		t.Fatalf("EnsureEmptyDir: %v", err)
+	}
+	if !out.IsDir() || !out.IsEmpty() {
+		t.Errorf("expected directory to be emptied")
+	}
+	if !outside.Exists() {
+		t.Errorf("expected symlink target to be left alone")
+	}
+	fi, err := out.Stat()
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if runtime.GOOS != "windows" && fi.Mode().Perm() != 0o750 {
+		t.Errorf("expected mode 0750 to be preserved, got %o", fi.Mode().Perm())
+	}
+
+	if err := outside.EnsureEmptyDir(); err == nil {
+		t.Errorf("expected error for a file, got nil")
+	}
+}
+
 func TestMkdirIfNotExist(t *testing.T) {
 	p := New("testdir")
 
@@ -317,6 +1154,31 @@ func TestMkdirIfNotExist(t *testing.T) {
 	p.Delete()
 }
 
+func TestMkdirIfNotExistConcurrent(t *testing.T) {
+	p := New(t.TempDir()).Join("a", "b", "c")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- p.MkdirIfNotExist()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if !p.IsDir() {
+		t.Errorf("expected path to be a directory")
+	}
+}
+
 func TestSizeX(t *testing.T) {
 	p := New("testfile.txt")
 	if err := p.WriteFile(testContent); err != nil {
@@ -396,6 +1258,88 @@ func TestIsWritable(t *testing.T) {
 	})
 }
 
+func TestAccessError(t *testing.T) {
+	tempDir := New(t.TempDir())
+	file := tempDir.Join("file.txt")
+	if err := file.WriteFile(testContent); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	for _, p := range []Path{file, tempDir, tempDir.Join("new.txt"), tempDir.Join("a", "b", "c.txt")} {
+		if err := p.AccessError(); err != nil {
+			t.Errorf("expected %s to be writable, got %v", p, err)
+		}
+	}
+
+	if err := file.Join("child").AccessError(); err == nil {
+		t.Errorf("expected error for a path below a file, got nil")
+	}
+
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		t.Skip("permission bits are not enforced")
+	}
+
+	readOnly := tempDir.Join("readonly.txt")
+	if err := readOnly.WriteFile(testContent); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.Chmod(readOnly.String(), 0o444); err != nil {
+		t.Fatalf("Chmod: %v", err)
+	}
+	if err := readOnly.AccessError(); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("expected permission error for read-only file, got %v", err)
+	}
+
+	locked := tempDir.Join("locked")
+	if err := locked.MkdirIfNotExist(); err != nil {
+		t.Fatalf("MkdirIfNotExist: %v", err)
+	}
+	if err := os.Chmod(locked.String(), 0o555); err != nil {
+		t.Fatalf("Chmod: %v", err)
+	}
+	defer os.Chmod(locked.String(), 0o755)
+
+	if err := locked.AccessError(); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("expected permission error for read-only directory, got %v", err)
+	}
+	err := locked.Join("x", "y.txt").AccessError()
+	if !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("expected permission error below read-only directory, got %v", err)
+	}
+	if err != nil && !strings.Contains(err.Error(), locked.Join("x").String()) {
+		t.Errorf("expected error to name the first missing component, got %v", err)
+	}
+}
+
+func TestIsEmpty(t *testing.T) {
+	tempDir := New(t.TempDir())
+
+	if !tempDir.Join("missing").IsEmpty() {
+		t.Errorf("expected non-existent path to be empty")
+	}
+	if !tempDir.IsEmpty() {
+		t.Errorf("expected new directory to be empty")
+	}
+
+	file := tempDir.Join("file.txt")
+	if err := file.WriteFile(nil); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if !file.IsEmpty() {
+		t.Errorf("expected zero-size file to be empty")
+	}
+	if tempDir.IsEmpty() {
+		t.Errorf("expected directory with a file to be non-empty")
+	}
+
+	if err := file.WriteFile(testContent); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if file.IsEmpty() {
+		t.Errorf("expected file with content to be non-empty")
+	}
+}
+
 func TestOpenFile(t *testing.T) {
 	p := New("testfile.txt")
 	defer p.Delete()
@@ -424,6 +1368,23 @@ func TestOpenFile(t *testing.T) {
 	if err == nil {
 		t.Errorf("expected error, got nil")
 	}
+
+	// Test creating a file whose parent directories do not exist yet
+	tempDir := New(t.TempDir())
+	nested := tempDir.Join("a", "b", "file.txt")
+	f, err = nested.OpenFile(os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	f.Close()
+
+	// Test opening a directory for reading and for writing
+	if _, err = tempDir.OpenFile(os.O_RDONLY, 0); err == nil {
+		t.Errorf("expected error opening a directory read-only, got nil")
+	}
+	if _, err = tempDir.OpenFile(os.O_WRONLY, 0); err == nil {
+		t.Errorf("expected error opening a directory for writing, got nil")
+	}
 }
 
 func TestJoinP(t *testing.T) {
@@ -537,6 +1498,24 @@ func TestReadDir(t *testing.T) {
 	}
 }
 
+func TestEntry(t *testing.T) {
+	p := New(t.TempDir())
+	if err := p.Join("file.txt").WriteFile(testContent); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	entries, err := p.ReadDir()
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	if entry := p.Entry(entries[0]); entry != p.Join("file.txt") {
+		t.Errorf("expected %s, got %s", p.Join("file.txt"), entry)
+	}
+}
+
 func TestBaseWithoutExt(t *testing.T) {
 	tests := []struct {
 		input    Path
@@ -624,6 +1603,32 @@ func TestThisDir(t *testing.T) {
 	}()
 }
 
+func TestRelative(t *testing.T) {
+	pkgDir := WD()
+	expected := pkgDir.Join("testdata", "fixture.json")
+
+	sub := New(t.TempDir()).Join("nested", "run")
+	if err := sub.MkdirIfNotExist(); err != nil {
+		t.Fatalf("MkdirIfNotExist: %v", err)
+	}
+	if err := os.Chdir(sub.String()); err != nil {
+		t.Fatalf("Chdir: %v", err)
+	}
+	defer os.Chdir(pkgDir.String())
+
+	if got := Relative("testdata", "fixture.json"); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+	func() {
+		if got := Relative("testdata", "fixture.json"); got != expected {
+			t.Errorf("expected %s from a closure, got %s", expected, got)
+		}
+	}()
+	if got := Relative(); got != pkgDir {
+		t.Errorf("expected %s, got %s", pkgDir, got)
+	}
+}
+
 func TestS(t *testing.T) {
 	tests := []struct {
 		input    Path
@@ -861,6 +1866,52 @@ func TestCopy(t *testing.T) {
 	})
 }
 
+func TestMoveInto(t *testing.T) {
+	tempDir := New(t.TempDir())
+	src := tempDir.Join("file.txt")
+	if err := src.WriteFile(testContent); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	dir := tempDir.Join("new", "dir")
+	moved, err := src.MoveInto(dir)
+	if err != nil {
+		t.Fatalf("MoveInto: %v", err)
+	}
+	if expected := dir.Join("file.txt"); moved != expected {
+		t.Errorf("expected %s, got %s", expected, moved)
+	}
+	if src.Exists() {
+		t.Errorf("expected source file to be moved")
+	}
+	if content, err := moved.ReadFile(); err != nil || string(content) != string(testContent) {
+		t.Errorf("expected %s, got %s, error: %v", testContent, content, err)
+	}
+
+	srcDir := tempDir.Join("tree")
+	if err := srcDir.Join("inner.txt").WriteFile(testContent); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	moved, err = srcDir.MoveInto(dir)
+	if err != nil {
+		t.Fatalf("MoveInto: %v", err)
+	}
+	if !moved.Join("inner.txt").IsRegular() {
+		t.Errorf("expected directory to be moved with its contents")
+	}
+
+	notDir := tempDir.Join("plain.txt")
+	if err := notDir.WriteFile(nil); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if _, err := moved.Join("inner.txt").MoveInto(notDir); err == nil {
+		t.Errorf("expected error when destination is a file, got nil")
+	}
+	if _, err := tempDir.Join("missing").MoveInto(dir); err == nil {
+		t.Errorf("expected error for missing source, got nil")
+	}
+}
+
 func TestMove(t *testing.T) {
 	t.Run("MoveFile", func(t *testing.T) {
 		src := New("srcfile.txt")
@@ -1008,6 +2059,132 @@ func TestOpenOrCreate(t *testing.T) {
 	}
 }
 
+func TestReaderWriter(t *testing.T) {
+	p := New(t.TempDir()).Join("sub", "file.txt")
+
+	w, err := p.Writer()
+	if err != nil {
+		t.Fatalf("Writer: %v", err)
+	}
+	if _, err := w.Write(testContent); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	r, err := p.Reader()
+	if err != nil {
+		t.Fatalf("Reader: %v", err)
+	}
+	defer r.Close()
+
+	if _, err := r.Seek(5, io.SeekStart); err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+	content, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if string(content) != string(testContent[5:]) {
+		t.Errorf("expected %s, got %s", testContent[5:], content)
+	}
+
+	if _, err := p.Dir().Join("missing").Reader(); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
+func TestWriteToPath(t *testing.T) {
+	tempDir := New(t.TempDir())
+	src := tempDir.Join("src.txt")
+	dst := tempDir.Join("dst.txt")
+	if err := src.WriteFile(testContent); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := dst.WriteFile([]byte("old destination content that is longer")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	if err := src.WriteToPath(dst); err != nil {
+		t.Fatalf("WriteToPath: %v", err)
+	}
+	content, err := dst.ReadFile()
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(content) != string(testContent) {
+		t.Errorf("expected %s, got %s", testContent, content)
+	}
+}
+
+func TestReadFromPath(t *testing.T) {
+	tempDir := New(t.TempDir())
+	src := tempDir.Join("src.txt")
+	dst := tempDir.Join("dst.txt")
+	if err := src.WriteFile(testContent); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := dst.WriteFile([]byte("old destination content that is longer")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	if err := dst.ReadFromPath(src); err != nil {
+		t.Fatalf("ReadFromPath: %v", err)
+	}
+	content, err := dst.ReadFile()
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(content) != string(testContent) {
+		t.Errorf("expected %s, got %s", testContent, content)
+	}
+
+	// ReadFrom keeps its create-only semantics
+	if err := dst.ReadFrom(bytes.NewReader(testContent)); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
+func TestAsReader(t *testing.T) {
+	p := New(t.TempDir()).Join("file.txt")
+
+	// Nothing is opened until the first Read.
+	r := p.AsReader()
+	if err := p.WriteFile(testContent); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	content, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if string(content) != string(testContent) {
+		t.Errorf("expected %s, got %s", testContent, content)
+	}
+	if n, err := r.Read(make([]byte, 1)); n != 0 || err != io.EOF {
+		t.Errorf("expected (0, EOF) after EOF, got (%d, %v)", n, err)
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	r = p.AsReader()
+	if _, err := r.Read(make([]byte, 1)); err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := r.Read(make([]byte, 1)); err == nil {
+		t.Errorf("expected error reading a closed reader, got nil")
+	}
+
+	if _, err := p.Dir().Join("missing").AsReader().Read(make([]byte, 1)); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
 func TestCreate(t *testing.T) {
 	t.Run("CreateNewFile", func(t *testing.T) {
 		p := New("testfile.txt")
@@ -1043,6 +2220,9 @@ func TestCreate(t *testing.T) {
 		if err.Error() != "already exists" {
 			t.Errorf("expected 'already exists' error, got %v", err)
 		}
+		if !errors.Is(err, os.ErrExist) {
+			t.Errorf("expected error to match os.ErrExist, got %v", err)
+		}
 	})
 
 	t.Run("CreateInNonExistentDirectory", func(t *testing.T) {
@@ -1064,6 +2244,193 @@ func TestCreate(t *testing.T) {
 	})
 }
 
+func TestRenameEach(t *testing.T) {
+	setup := func(t *testing.T, names ...string) Path {
+		dir := New(t.TempDir())
+		for _, name := range names {
+			if err := dir.Join(name).WriteFile([]byte(name)); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+		}
+		return dir
+	}
+	contents := func(t *testing.T, dir Path) map[string]string {
+		entries, err := dir.ReadDir()
+		if err != nil {
+			t.Fatalf("ReadDir: %v", err)
+		}
+		m := make(map[string]string)
+		for _, e := range entries {
+			data, _ := dir.Entry(e).ReadFile()
+			m[e.Name()] = string(data)
+		}
+		return m
+	}
+
+	t.Run("Lowercase", func(t *testing.T) {
+		dir := setup(t, "Foo.TXT", "bar.txt", "IMG_1.JPG")
+		n, err := dir.RenameEach(strings.ToLower)
+		if err != nil || n != 2 {
+			t.Fatalf("expected 2 renamed, got %d, error: %v", n, err)
+		}
+		expected := map[string]string{"foo.txt": "Foo.TXT", "bar.txt": "bar.txt", "img_1.jpg": "IMG_1.JPG"}
+		if got := contents(t, dir); !maps.Equal(got, expected) {
+			t.Errorf("expected %v, got %v", expected, got)
+		}
+	})
+
+	t.Run("Swap", func(t *testing.T) {
+		dir := setup(t, "a", "b", "c")
+		n, err := dir.RenameEach(func(name string) string {
+			return map[string]string{"a": "b", "b": "a"}[name]
+		})
+		if err != nil || n != 2 {
+			t.Fatalf("expected 2 renamed, got %d, error: %v", n, err)
+		}
+		expected := map[string]string{"a": "b", "b": "a", "c": "c"}
+		if got := contents(t, dir); !maps.Equal(got, expected) {
+			t.Errorf("expected %v, got %v", expected, got)
+		}
+	})
+
+	failures := []struct {
+		name  string
+		files []string
+		fn    func(string) string
+	}{
+		{"SameTarget", []string{"x1", "x2"}, func(string) string { return "x" }},
+		{"TargetsDifferInCase", []string{"a", "b"}, func(s string) string { return map[string]string{"a": "n", "b": "N"}[s] }},
+		{"ExistingEntry", []string{"old_a", "a"}, func(s string) string { return strings.TrimPrefix(s, "old_") }},
+		{"UnsafeName", []string{"a", "b"}, func(s string) string { return "../" + s }},
+	}
+	for _, tt := range failures {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := setup(t, tt.files...)
+			before := contents(t, dir)
+			if n, err := dir.RenameEach(tt.fn); err == nil || n != 0 {
+				t.Errorf("expected error and nothing renamed, got %d, error: %v", n, err)
+			}
+			if got := contents(t, dir); !maps.Equal(got, before) {
+				t.Errorf("expected no changes, got %v", got)
+			}
+		})
+	}
+
+	if _, err := New(t.TempDir(), "missing").RenameEach(strings.ToLower); err == nil {
+		t.Errorf("expected error for a missing directory, got nil")
+	}
+}
+
+func TestUniqueChild(t *testing.T) {
+	dir := New(t.TempDir())
+
+	seen := map[Path]bool{}
+	for range 100 {
+		p, err := dir.UniqueChild("out-", ".json")
+		if err != nil {
+			t.Fatalf("UniqueChild: %v", err)
+		}
+		if p.Dir() != dir {
+			t.Errorf("expected parent %s, got %s", dir, p.Dir())
+		}
+		if !p.Base().HasPrefix("out-") || !p.HasSuffix(".json") {
+			t.Errorf("expected out-*.json, got %s", p.Base())
+		}
+		if p.LExists() {
+			t.Errorf("expected %s not to exist", p)
+		}
+		if seen[p] {
+			t.Errorf("expected unique names, got %s twice", p)
+		}
+		seen[p] = true
+		if err := p.WriteFile(nil); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	if _, err := dir.UniqueChild("sub/", ""); err == nil {
+		t.Errorf("expected error for separator in prefix, got nil")
+	}
+}
+
+func TestCreateConcurrent(t *testing.T) {
+	p := New(t.TempDir(), "shared.txt")
+
+	const workers = 32
+	var (
+		wg      sync.WaitGroup
+		created = make(chan int, workers)
+	)
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f, err := p.Create()
+			if err != nil {
+				if err.Error() != "already exists" {
+					t.Errorf("expected 'already exists' error, got %v", err)
+				}
+				return
+			}
+			defer f.Close()
+			if _, err := fmt.Fprintf(f, "worker %d", i); err != nil {
+				t.Errorf("write: %v", err)
+			}
+			created <- i
+		}()
+	}
+	wg.Wait()
+	close(created)
+
+	var winners []int
+	for i := range created {
+		winners = append(winners, i)
+	}
+	if len(winners) != 1 {
+		t.Fatalf("expected exactly one creator, got %d", len(winners))
+	}
+	content, err := p.ReadFile()
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if expected := fmt.Sprintf("worker %d", winners[0]); string(content) != expected {
+		t.Errorf("expected %s, got %s", expected, content)
+	}
+}
+
+func TestCreateExclusive(t *testing.T) {
+	p := New(t.TempDir(), "lock", "owner")
+
+	const workers = 8
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		created int
+	)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f, err := p.CreateExclusive()
+			if err != nil {
+				if !errors.Is(err, os.ErrExist) {
+					t.Errorf("expected os.ErrExist, got %v", err)
+				}
+				return
+			}
+			f.Close()
+			mu.Lock()
+			created++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if created != 1 {
+		t.Errorf("expected exactly one creator, got %d", created)
+	}
+}
+
 func TestUsage(t *testing.T) {
 	// Test Usage on an existing directory
 	t.Run("ExistingDirectory", func(t *testing.T) {
@@ -1084,6 +2451,10 @@ func TestUsage(t *testing.T) {
 		if usage.UsedPercent == 0 {
 			t.Errorf("expected non-zero used percent")
 		}
+		if usage.InodesTotal != 0 && usage.InodesUsed+usage.InodesFree != usage.InodesTotal {
+			t.Errorf("expected used + free inodes to equal total, got %d + %d != %d",
+				usage.InodesUsed, usage.InodesFree, usage.InodesTotal)
+		}
 	})
 
 	// Test Usage on a non-existent path
@@ -1256,6 +2627,7 @@ func TestQuery(t *testing.T) {
 		{New("/example/path/for/test"), ""},
 		{New("/example/path/for/test?"), ""},
 		{New("/example/path/for/test?foo="), "foo="},
+		{New("/example/path/for/test?next=/a?b=c"), "next=/a?b=c"},
 	}
 
 	for _, test := range tests {
@@ -1327,6 +2699,35 @@ func TestHasQuery(t *testing.T) {
 	}
 }
 
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		path     Path
+		expected Path
+		query    url.Values
+		fragment string
+	}{
+		{"/a/b?x=1&y=2#frag", "/a/b", url.Values{"x": {"1"}, "y": {"2"}}, "frag"},
+		{"/a/b?x=1&x=2", "/a/b", url.Values{"x": {"1", "2"}}, ""},
+		{"/a/b#frag?not=query", "/a/b", url.Values{}, "frag?not=query"},
+		{"/a/b?next=%2Fc%3Fd", "/a/b", url.Values{"next": {"/c?d"}}, ""},
+		{"/a/b", "/a/b", url.Values{}, ""},
+		{"/a/b?x=1;y=2&z=3", "/a/b", url.Values{"z": {"3"}}, ""},
+	}
+
+	for _, tt := range tests {
+		path, query, fragment := tt.path.ParseURL()
+		if path != tt.expected {
+			t.Errorf("expected path %s, got %s for %s", tt.expected, path, tt.path)
+		}
+		if query.Encode() != tt.query.Encode() {
+			t.Errorf("expected query %v, got %v for %s", tt.query, query, tt.path)
+		}
+		if fragment != tt.fragment {
+			t.Errorf("expected fragment %s, got %s for %s", tt.fragment, fragment, tt.path)
+		}
+	}
+}
+
 func TestMergeMove_SourceDoesNotExist(t *testing.T) {
 	src := New("nonexistent.txt")
 	dst := New("dst.txt")
@@ -1517,3 +2918,190 @@ func TestMergeMove_DirectoryToNonDirectory(t *testing.T) {
 		t.Fatal("expected error when moving directory to non-directory, got nil")
 	}
 }
+
+func TestInfo(t *testing.T) {
+	tempDir := New(t.TempDir())
+	file := tempDir.Join("file.txt")
+	if err := file.WriteFile(testContent); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	info, err := file.Info()
+	if err != nil {
+		t.Fatalf("Info: %v", err)
+	}
+	if !info.IsRegular() || info.IsDir() || info.IsDev() {
+		t.Errorf("expected a regular file")
+	}
+	if info.Size() != int64(len(testContent)) {
+		t.Errorf("expected size %d, got %d", len(testContent), info.Size())
+	}
+
+	info, err = tempDir.Info()
+	if err != nil {
+		t.Fatalf("Info: %v", err)
+	}
+	if !info.IsDir() || info.IsRegular() {
+		t.Errorf("expected a directory")
+	}
+
+	if _, err := tempDir.Join("missing").Info(); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
+func BenchmarkOpenFile(b *testing.B) {
+	p := New(b.TempDir()).Join("bench.txt")
+	b.ResetTimer()
+	for range b.N {
+		f, err := p.OpenFile(os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+		if err != nil {
+			b.Fatalf("OpenFile: %v", err)
+		}
+		f.Close()
+	}
+}
+
+func TestMergeMove_Symlinks(t *testing.T) {
+	tempDir := New(t.TempDir())
+	target := tempDir.Join("target")
+	if err := target.Join("file.txt").WriteFile([]byte("target content")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	srcDir := tempDir.Join("srcDir")
+	if err := srcDir.Join("file.txt").WriteFile([]byte("file content")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.Symlink(target.String(), srcDir.Join("dirlink").String()); err != nil {
+		t.Fatalf("os.Symlink: %v", err)
+	}
+	if err := os.Symlink(target.Join("file.txt").String(), srcDir.Join("filelink").String()); err != nil {
+		t.Fatalf("os.Symlink: %v", err)
+	}
+	if err := os.Symlink(tempDir.Join("missing").String(), srcDir.Join("dangling").String()); err != nil {
+		t.Fatalf("os.Symlink: %v", err)
+	}
+
+	dstDir := tempDir.Join("dstDir")
+	if err := dstDir.Join("dirlink").MkdirIfNotExist(); err != nil {
+		t.Fatalf("MkdirIfNotExist: %v", err)
+	}
+
+	if err := srcDir.MergeMove(dstDir); err != nil {
+		t.Fatalf("MergeMove: %v", err)
+	}
+	if srcDir.Exists() {
+		t.Errorf("expected source directory to be deleted")
+	}
+
+	// The symlinked directory must not be merged into, nor lose its contents.
+	if !target.Join("file.txt").Exists() {
+		t.Errorf("expected symlink target to be left untouched")
+	}
+	if movedLink := dstDir.Join("dirlink", "dirlink"); !movedLink.IsSymlink() {
+		t.Errorf("expected %s to be a symlink", movedLink)
+	}
+	for _, name := range []string{"filelink", "dangling"} {
+		if movedLink := dstDir.Join(name); !movedLink.IsSymlink() {
+			t.Errorf("expected %s to be a symlink", movedLink)
+		}
+	}
+	content, err := dstDir.Join("filelink").ReadFile()
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(content) != "target content" {
+		t.Errorf("expected %q, got %q", "target content", content)
+	}
+}
+
+func TestMergeMoveWith(t *testing.T) {
+	old := time.Now().Add(-time.Hour)
+	setup := func(t *testing.T) (Path, Path) {
+		tempDir := New(t.TempDir())
+		src, dst := tempDir.Join("src"), tempDir.Join("dst")
+		if err := src.Join("older.txt").WriteFile([]byte("src older")); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		if err := src.Join("newer.txt").WriteFile([]byte("src newer")); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		if err := src.Join("only.txt").WriteFile([]byte("src only")); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		if err := dst.Join("older.txt").WriteFile([]byte("dst newer")); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		if err := dst.Join("newer.txt").WriteFile([]byte("dst older")); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		for _, p := range []Path{src.Join("older.txt"), dst.Join("newer.txt")} {
+			if err := os.Chtimes(p.String(), old, old); err != nil {
+				t.Fatalf("os.Chtimes: %v", err)
+			}
+		}
+		return src, dst
+	}
+	check := func(t *testing.T, p Path, expected string) {
+		t.Helper()
+		content, err := p.ReadFile()
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		if string(content) != expected {
+			t.Errorf("expected %q in %s, got %q", expected, p, content)
+		}
+	}
+
+	t.Run("Overwrite", func(t *testing.T) {
+		src, dst := setup(t)
+		if err := src.MergeMoveWith(dst, ConflictOverwrite); err != nil {
+			t.Fatalf("MergeMoveWith: %v", err)
+		}
+		check(t, dst.Join("older.txt"), "src older")
+		check(t, dst.Join("newer.txt"), "src newer")
+		check(t, dst.Join("only.txt"), "src only")
+		if src.Exists() {
+			t.Errorf("expected source directory to be deleted")
+		}
+	})
+
+	t.Run("Skip", func(t *testing.T) {
+		src, dst := setup(t)
+		if err := src.MergeMoveWith(dst, ConflictSkip); err != nil {
+			t.Fatalf("MergeMoveWith: %v", err)
+		}
+		check(t, dst.Join("older.txt"), "dst newer")
+		check(t, dst.Join("newer.txt"), "dst older")
+		check(t, dst.Join("only.txt"), "src only")
+		check(t, src.Join("older.txt"), "src older")
+	})
+
+	t.Run("KeepNewer", func(t *testing.T) {
+		src, dst := setup(t)
+		if err := src.MergeMoveWith(dst, ConflictKeepNewer); err != nil {
+			t.Fatalf("MergeMoveWith: %v", err)
+		}
+		check(t, dst.Join("older.txt"), "dst newer")
+		check(t, dst.Join("newer.txt"), "src newer")
+		check(t, dst.Join("only.txt"), "src only")
+	})
+
+	t.Run("Rename", func(t *testing.T) {
+		src, dst := setup(t)
+		if err := dst.Join("older (1).txt").WriteFile([]byte("taken")); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		if err := src.MergeMoveWith(dst, ConflictRename); err != nil {
+			t.Fatalf("MergeMoveWith: %v", err)
+		}
+		check(t, dst.Join("older.txt"), "dst newer")
+		check(t, dst.Join("older (1).txt"), "taken")
+		check(t, dst.Join("older (2).txt"), "src older")
+		check(t, dst.Join("newer (1).txt"), "src newer")
+		if src.Exists() {
+			t.Errorf("expected source directory to be deleted")
+		}
+	})
+}
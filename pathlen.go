@@ -0,0 +1,37 @@
+package ppath
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// maxNameLength is the longest single path component most filesystems
+// accept: 255 bytes on ext4, APFS and friends, 255 UTF-16 units on NTFS.
+const maxNameLength = 255
+
+// MaxPathLength returns the OS limit on the length of p, including the
+// terminating NUL the OS counts: 4096 bytes on Linux, 1024 on macOS and 260
+// UTF-16 units on Windows, or 32767 when p carries the \\?\ long-path prefix.
+func (p Path) MaxPathLength() int {
+	return maxPathLength(string(p))
+}
+
+// IsPathTooLong reports whether the OS would reject p for its length, either
+// as a whole (see MaxPathLength) or because one of its components exceeds
+// 255 units. It lets callers fail with a clear message before os.Create
+// returns ENAMETOOLONG or a less obvious error. On Windows the whole length
+// is measured on the absolute path, since relative paths are expanded
+// against the working directory before the limit applies.
+func (p Path) IsPathTooLong() bool {
+	if pathLength(string(p)) >= p.MaxPathLength() {
+		return true
+	}
+
+	rest := string(p)[len(filepath.VolumeName(string(p))):]
+	for _, name := range strings.FieldsFunc(rest, func(r rune) bool { return r == '/' || r == filepath.Separator }) {
+		if nameLength(name) > maxNameLength {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,18 @@
+//go:build darwin
+
+package ppath
+
+// pathMax is PATH_MAX from <sys/syslimits.h>.
+const pathMax = 1024
+
+func maxPathLength(string) int {
+	return pathMax
+}
+
+func pathLength(path string) int {
+	return len(path)
+}
+
+func nameLength(name string) int {
+	return len(name)
+}
@@ -0,0 +1,18 @@
+//go:build linux
+
+package ppath
+
+// pathMax is PATH_MAX from <linux/limits.h>.
+const pathMax = 4096
+
+func maxPathLength(string) int {
+	return pathMax
+}
+
+func pathLength(path string) int {
+	return len(path)
+}
+
+func nameLength(name string) int {
+	return len(name)
+}
@@ -0,0 +1,37 @@
+package ppath
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsPathTooLong(t *testing.T) {
+	dir := New(t.TempDir())
+	limit := dir.MaxPathLength()
+	if limit <= 0 {
+		t.Fatalf("expected a positive limit, got %d", limit)
+	}
+
+	tests := []struct {
+		name     string
+		path     Path
+		expected bool
+	}{
+		{"short", dir.Join("a", "b.txt"), false},
+		{"long component", dir.Join(strings.Repeat("x", maxNameLength+1)), true},
+		{"max component", dir.Join(strings.Repeat("x", maxNameLength)), false},
+		{"long path", dir.Join(strings.Repeat(strings.Repeat("x", 100)+"/", limit/101+1)), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.path.IsPathTooLong(); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	long := dir.Join(strings.Repeat("x", maxNameLength+1))
+	if err := long.WriteFile(nil); err == nil {
+		t.Errorf("expected the OS to reject %d-byte name", maxNameLength+1)
+	}
+}
@@ -0,0 +1,42 @@
+//go:build windows
+
+package ppath
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	// maxPath is the classic MAX_PATH limit.
+	maxPath = 260
+	// maxLongPath applies to paths using the \\?\ prefix, which bypasses
+	// MAX_PATH.
+	maxLongPath = 32767
+)
+
+func maxPathLength(path string) int {
+	if strings.HasPrefix(path, `\\?\`) {
+		return maxLongPath
+	}
+	return maxPath
+}
+
+func pathLength(path string) int {
+	if !strings.HasPrefix(path, `\\?\`) {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+	}
+	return nameLength(path)
+}
+
+// nameLength counts UTF-16 code units, the unit Windows limits are in.
+func nameLength(name string) int {
+	n := 0
+	for _, r := range name {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
@@ -0,0 +1,22 @@
+package ppath
+
+import "slices"
+
+// Paths is a list of paths with helpers for operating on all of them.
+type Paths []Path
+
+// Sort sorts the paths in place according to Path.Compare.
+func (ps Paths) Sort() {
+	slices.SortFunc(ps, Path.Compare)
+}
+
+// SortNatural sorts the paths in place according to Path.CompareNatural, so
+// "img2.png" comes before "img10.png".
+func (ps Paths) SortNatural() {
+	slices.SortFunc(ps, Path.CompareNatural)
+}
+
+// SortNaturalFold is like SortNatural but ignores case.
+func (ps Paths) SortNaturalFold() {
+	slices.SortFunc(ps, compareNaturalFold)
+}
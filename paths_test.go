@@ -0,0 +1,77 @@
+package ppath
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPathsSort(t *testing.T) {
+	ps := Paths{"b", "a/b", "a", "a-b"}
+	ps.Sort()
+	if expected := (Paths{"a", "a/b", "a-b", "b"}); !slices.Equal(ps, expected) {
+		t.Errorf("expected %v, got %v", expected, ps)
+	}
+}
+
+func TestPathsSortNatural(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       Paths
+		expected Paths
+		sort     func(Paths)
+	}{
+		{
+			name:     "Versions",
+			in:       Paths{"v10", "v2", "v1", "v11", "v9", "v3"},
+			expected: Paths{"v1", "v2", "v3", "v9", "v10", "v11"},
+			sort:     Paths.SortNatural,
+		},
+		{
+			name:     "NestedSegments",
+			in:       Paths{"v10/a", "v2/b10", "v2/b9", "v1/x"},
+			expected: Paths{"v1/x", "v2/b9", "v2/b10", "v10/a"},
+			sort:     Paths.SortNatural,
+		},
+		{
+			name:     "MixedAlphaNumeric",
+			in:       Paths{"img10b.png", "img10a.png", "img2.png", "img.png"},
+			expected: Paths{"img.png", "img2.png", "img10a.png", "img10b.png"},
+			sort:     Paths.SortNatural,
+		},
+		{
+			name:     "LeadingZeros",
+			in:       Paths{"page010", "page9", "page01", "page1"},
+			expected: Paths{"page1", "page01", "page9", "page010"},
+			sort:     Paths.SortNatural,
+		},
+		{
+			name:     "CaseSensitive",
+			in:       Paths{"b2", "B10", "a1"},
+			expected: Paths{"B10", "a1", "b2"},
+			sort:     Paths.SortNatural,
+		},
+		{
+			name:     "CaseInsensitive",
+			in:       Paths{"b2", "B10", "a1", "A1"},
+			expected: Paths{"A1", "a1", "b2", "B10"},
+			sort:     Paths.SortNaturalFold,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps := slices.Clone(tt.in)
+			tt.sort(ps)
+			if !slices.Equal(ps, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, ps)
+			}
+		})
+	}
+
+	if !NaturalLess("frame2", "frame10") || NaturalLess("frame10", "frame2") {
+		t.Errorf("expected frame2 to sort before frame10")
+	}
+	if !NaturalLessFold("Frame2", "frame10") {
+		t.Errorf("expected Frame2 to sort before frame10 ignoring case")
+	}
+}
@@ -0,0 +1,138 @@
+package ppath
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"runtime"
+	"slices"
+	"sync"
+
+	"github.com/maa3x/errz"
+)
+
+const (
+	defaultFileMode os.FileMode = 0o644
+	defaultDirMode  os.FileMode = 0o755
+)
+
+var (
+	modeMu   sync.RWMutex
+	filePerm = defaultFileMode
+	dirPerm  = defaultDirMode
+)
+
+// SetDefaultFileMode sets the permission bits that the helpers creating files
+// without an explicit mode use, such as WriteFile, WriteJSON, Writer and the
+// copy destinations. Only mode.Perm() is kept, and the process umask still
+// applies at creation as with os.OpenFile; existing files keep their mode.
+// Passing 0 restores the default 0o644. It affects every Path in the process
+// and is safe for concurrent use; OpenFile and WriteFileMode still take the
+// mode they are given, and Create uses 0o666 like os.Create.
+func SetDefaultFileMode(mode os.FileMode) {
+	if mode.Perm() == 0 {
+		mode = defaultFileMode
+	}
+
+	modeMu.Lock()
+	filePerm = mode.Perm()
+	modeMu.Unlock()
+}
+
+// SetDefaultDirMode sets the permission bits that MkdirIfNotExist,
+// MkdirAllResolved and the helpers creating parent directories use. Only
+// mode.Perm() is kept and the umask still applies. Passing 0 restores the
+// default 0o755. It is safe for concurrent use; MkdirIfNotExistMode still
+// takes the mode it is given.
+func SetDefaultDirMode(mode os.FileMode) {
+	if mode.Perm() == 0 {
+		mode = defaultDirMode
+	}
+
+	modeMu.Lock()
+	dirPerm = mode.Perm()
+	modeMu.Unlock()
+}
+
+func defaultFilePerm() os.FileMode {
+	modeMu.RLock()
+	defer modeMu.RUnlock()
+	return filePerm
+}
+
+func defaultDirPerm() os.FileMode {
+	modeMu.RLock()
+	defer modeMu.RUnlock()
+	return dirPerm
+}
+
+// ChmodTree sets dirMode on p and every directory below it and fileMode on
+// every regular file. Symlinks and other special files are left alone, so no
+// link target is changed. Directory modes are applied after their contents,
+// so modes without read or search permission can be set as well. Failures are
+// collected and returned together once the rest of the tree is done.
+func (p Path) ChmodTree(dirMode, fileMode os.FileMode) error {
+	var (
+		dirs []string
+		errs []error
+	)
+	err := p.Walk(func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d == nil && path == string(p) {
+				return err
+			}
+			errs = append(errs, errz.E(err, "walk").With("path", path))
+			return nil
+		}
+
+		switch {
+		case d.IsDir():
+			dirs = append(dirs, path)
+		case d.Type().IsRegular():
+			if err := os.Chmod(path, fileMode); err != nil {
+				errs = append(errs, errz.E(err, "chmod").With("path", path))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errz.E(err, "walk directory")
+	}
+
+	for _, dir := range slices.Backward(dirs) {
+		if err := os.Chmod(dir, dirMode); err != nil {
+			errs = append(errs, errz.E(err, "chmod").With("path", dir))
+		}
+	}
+	return errz.Join(errs...)
+}
+
+// ChownTree sets the owner and group of p and every entry below it. Symlinks
+// are changed themselves with lchown and never followed. As with os.Chown, an
+// id of -1 leaves that value unchanged. Failures are collected and returned
+// together once the rest of the tree is done. On Windows, which has no
+// numeric owners, it fails with errors.ErrUnsupported without walking.
+func (p Path) ChownTree(uid, gid int) error {
+	if runtime.GOOS == "windows" {
+		return errz.E(errors.ErrUnsupported, "chown is not supported on windows")
+	}
+
+	var errs []error
+	err := p.Walk(func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d == nil && path == string(p) {
+				return err
+			}
+			errs = append(errs, errz.E(err, "walk").With("path", path))
+			return nil
+		}
+		if err := os.Lchown(path, uid, gid); err != nil {
+			errs = append(errs, errz.E(err, "chown").With("path", path))
+		}
+		return nil
+	})
+	if err != nil {
+		return errz.E(err, "walk directory")
+	}
+	return errz.Join(errs...)
+}
@@ -0,0 +1,163 @@
+package ppath
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestChmodTree(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits are not supported")
+	}
+
+	root := New(t.TempDir(), "tree")
+	outside := New(t.TempDir(), "outside.txt")
+	for _, p := range []Path{root.Join("a.txt"), root.Join("sub", "b.txt"), outside} {
+		if err := p.WriteFile(testContent); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	if err := os.Chmod(outside.String(), 0o600); err != nil {
+		t.Fatalf("os.Chmod: %v", err)
+	}
+	if err := os.Symlink(outside.String(), root.Join("link").String()); err != nil {
+		t.Fatalf("os.Symlink: %v", err)
+	}
+
+	if err := root.ChmodTree(0o750, 0o640); err != nil {
+		t.Fatalf("ChmodTree: %v", err)
+	}
+	for p, expected := range map[Path]fs.FileMode{
+		root:                      0o750,
+		root.Join("sub"):          0o750,
+		root.Join("a.txt"):        0o640,
+		root.Join("sub", "b.txt"): 0o640,
+		outside:                   0o600,
+	} {
+		fi, err := p.Stat()
+		if err != nil {
+			t.Fatalf("Stat: %v", err)
+		}
+		if fi.Mode().Perm() != expected {
+			t.Errorf("expected mode %o for %s, got %o", expected, p, fi.Mode().Perm())
+		}
+	}
+
+	// Directory modes are applied last, so even unreadable ones can be set.
+	if err := root.ChmodTree(0o300, 0o600); err != nil {
+		t.Fatalf("ChmodTree: %v", err)
+	}
+	t.Cleanup(func() {
+		os.Chmod(root.String(), 0o755)
+		os.Chmod(root.Join("sub").String(), 0o755)
+	})
+	for _, p := range []Path{root, root.Join("sub")} {
+		fi, err := p.Stat()
+		if err != nil {
+			t.Fatalf("Stat: %v", err)
+		}
+		if fi.Mode().Perm() != 0o300 {
+			t.Errorf("expected mode 300 for %s, got %o", p, fi.Mode().Perm())
+		}
+	}
+
+	if err := root.Join("missing").ChmodTree(0o755, 0o644); err == nil {
+		t.Errorf("expected error for a missing path, got nil")
+	}
+}
+
+func TestChownTree(t *testing.T) {
+	root := New(t.TempDir())
+	if err := root.Join("sub", "a.txt").WriteFile(testContent); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	err := root.ChownTree(os.Getuid(), os.Getgid())
+	if runtime.GOOS == "windows" {
+		if !errors.Is(err, errors.ErrUnsupported) {
+			t.Errorf("expected ErrUnsupported, got %v", err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("ChownTree: %v", err)
+	}
+
+	if err := root.Join("missing").ChownTree(-1, -1); err == nil {
+		t.Errorf("expected error for a missing path, got nil")
+	}
+}
+
+func TestDefaultModes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits are not supported")
+	}
+
+	SetDefaultFileMode(0o640)
+	SetDefaultDirMode(0o750)
+	defer SetDefaultFileMode(0)
+	defer SetDefaultDirMode(0)
+
+	root := New(t.TempDir())
+	file := root.Join("sub", "config.json")
+	if err := file.WriteFile(testContent); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	written := root.Join("other", "data.json")
+	if err := written.WriteJSON(map[string]int{"a": 1}); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	custom := root.Join("custom.txt")
+	if err := custom.WriteFileMode(testContent, 0o600); err != nil {
+		t.Fatalf("WriteFileMode: %v", err)
+	}
+	customDir := root.Join("custom")
+	if err := customDir.MkdirIfNotExistMode(0o700); err != nil {
+		t.Fatalf("MkdirIfNotExistMode: %v", err)
+	}
+
+	tests := []struct {
+		path     Path
+		expected fs.FileMode
+	}{
+		{file, 0o640},
+		{file.Dir(), 0o750},
+		{written, 0o640},
+		{written.Dir(), 0o750},
+		{custom, 0o600},
+		{customDir, 0o700},
+	}
+	for _, tt := range tests {
+		fi, err := tt.path.Stat()
+		if err != nil {
+			t.Fatalf("Stat: %v", err)
+		}
+		if got := fi.Mode().Perm(); got != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.path, tt.expected, got)
+		}
+	}
+
+	SetDefaultFileMode(0)
+	restored := root.Join("restored.txt")
+	if err := restored.WriteFile(testContent); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	reference := root.Join("reference.txt")
+	if err := os.WriteFile(reference.String(), testContent, 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	got, err := restored.Stat()
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	expected, err := reference.Stat()
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if got.Mode().Perm() != expected.Mode().Perm() {
+		t.Errorf("expected the default mode %v to be restored, got %v", expected.Mode().Perm(), got.Mode().Perm())
+	}
+}
@@ -0,0 +1,99 @@
+package ppath
+
+import (
+	"errors"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/maa3x/errz"
+)
+
+// ErrUnsafeMember is returned by SafeExtractPath for archive member names that
+// could be written outside the extraction root.
+var ErrUnsafeMember = errors.New("unsafe archive member")
+
+// SafeExtractPath returns where the archive member named member should be
+// extracted below root. Backslashes are treated as separators and a leading
+// drive letter such as "C:" is removed, then the name is cleaned. Names that
+// are empty, absolute, contain a NUL byte, escape root through "..", or are
+// not valid local names on this platform are rejected with an error matching
+// ErrUnsafeMember that names the member. The result is always root itself or
+// a path inside it.
+func SafeExtractPath(root Path, member string) (Path, error) {
+	unsafe := func() (Path, error) {
+		return "", errz.E(ErrUnsafeMember).With("member", member)
+	}
+
+	name := strings.ReplaceAll(member, `\`, "/")
+	if len(name) >= 2 && name[1] == ':' && isASCIILetter(name[0]) {
+		name = name[2:]
+	}
+	if name == "" || strings.HasPrefix(name, "/") || strings.Contains(name, "\x00") {
+		return unsafe()
+	}
+
+	name = path.Clean(name)
+	if name == ".." || strings.HasPrefix(name, "../") {
+		return unsafe()
+	}
+	if name == "." {
+		return root, nil
+	}
+	local, err := filepath.Localize(name)
+	if err != nil {
+		return unsafe()
+	}
+	return root.Join(local), nil
+}
+
+func isASCIILetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// IsSafeSegment reports whether s can be joined to a directory as a single
+// path component without escaping it or naming something unexpected. It
+// rejects empty strings, "." and "..", anything containing a slash, backslash
+// or NUL byte, on every platform so that names checked on one system stay
+// safe on another. On Windows it also rejects reserved device names such as
+// "CON" or "com1.txt", names ending in a dot or space, and characters Windows
+// does not allow in file names, including the colon used for volume names and
+// alternate data streams.
+func IsSafeSegment(s string) bool {
+	return isSafeSegment(s, runtime.GOOS == "windows")
+}
+
+func isSafeSegment(s string, windows bool) bool {
+	if s == "" || s == "." || s == ".." || strings.ContainsAny(s, "/\\\x00") {
+		return false
+	}
+	if !windows {
+		return true
+	}
+
+	for _, r := range s {
+		if r < 0x20 || strings.ContainsRune(`<>:"|?*`, r) {
+			return false
+		}
+	}
+	if strings.HasSuffix(s, ".") || strings.HasSuffix(s, " ") {
+		return false
+	}
+	return !isReservedWindowsName(s)
+}
+
+// isReservedWindowsName reports whether s names a DOS device, which Windows
+// resolves regardless of directory and extension.
+func isReservedWindowsName(s string) bool {
+	base, _, _ := strings.Cut(s, ".")
+	base = strings.ToUpper(strings.TrimRight(base, " "))
+	switch base {
+	case "CON", "PRN", "AUX", "NUL", "CONIN$", "CONOUT$":
+		return true
+	}
+	if len(base) == 4 && (strings.HasPrefix(base, "COM") || strings.HasPrefix(base, "LPT")) {
+		return '1' <= base[3] && base[3] <= '9'
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package ppath
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestIsSafeSegment(t *testing.T) {
+	tests := []struct {
+		s       string
+		unix    bool
+		windows bool
+	}{
+		{"report.pdf", true, true},
+		{".hidden", true, true},
+		{"..data", true, true},
+		{"", false, false},
+		{".", false, false},
+		{"..", false, false},
+		{"a/b", false, false},
+		{"/etc", false, false},
+		{`a\b`, false, false},
+		{`C:\x`, false, false},
+		{"a\x00b", false, false},
+		{"file.txt:stream", true, false},
+		{"C:", true, false},
+		{"what?", true, false},
+		{"trailing.", true, false},
+		{"trailing ", true, false},
+		{"CON", true, false},
+		{"con.txt", true, false},
+		{"Com1.log", true, false},
+		{"LPT9", true, false},
+		{"COM0", true, true},
+		{"CONSOLE", true, true},
+		{"nul .txt", true, false},
+	}
+
+	for _, tt := range tests {
+		if got := isSafeSegment(tt.s, false); got != tt.unix {
+			t.Errorf("isSafeSegment(%q, unix): expected %v, got %v", tt.s, tt.unix, got)
+		}
+		if got := isSafeSegment(tt.s, true); got != tt.windows {
+			t.Errorf("isSafeSegment(%q, windows): expected %v, got %v", tt.s, tt.windows, got)
+		}
+	}
+}
+
+func TestSafeExtractPath(t *testing.T) {
+	root := New(t.TempDir())
+
+	tests := []struct {
+		member   string
+		expected Path
+	}{
+		{"a.txt", root.Join("a.txt")},
+		{"dir/sub/b.txt", root.Join("dir", "sub", "b.txt")},
+		{`dir\win\c.txt`, root.Join("dir", "win", "c.txt")},
+		{"./d.txt", root.Join("d.txt")},
+		{"dir/../e.txt", root.Join("e.txt")},
+		{"dir/", root.Join("dir")},
+		{"./", root},
+		{"C:relative.txt", root.Join("relative.txt")},
+	}
+	for _, tt := range tests {
+		got, err := SafeExtractPath(root, tt.member)
+		if err != nil {
+			t.Errorf("SafeExtractPath(%q): %v", tt.member, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("SafeExtractPath(%q): expected %s, got %s", tt.member, tt.expected, got)
+		}
+	}
+
+	for _, member := range []string{"", "/etc/passwd", "../evil", "a/../../evil", `..\evil`, `C:\Windows\evil`, "//server/share", "a\x00b"} {
+		_, err := SafeExtractPath(root, member)
+		if !errors.Is(err, ErrUnsafeMember) {
+			t.Errorf("SafeExtractPath(%q): expected ErrUnsafeMember, got %v", member, err)
+			continue
+		}
+		if member != "" && !strings.Contains(err.Error(), member) {
+			t.Errorf("expected error to name %q, got %v", member, err)
+		}
+	}
+}
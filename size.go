@@ -0,0 +1,113 @@
+package ppath
+
+import (
+	"fmt"
+	"io/fs"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/maa3x/errz"
+)
+
+var (
+	iecUnits = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+	siUnits  = []string{"B", "kB", "MB", "GB", "TB", "PB", "EB"}
+)
+
+// HumanSize formats n bytes using IEC units (KiB, MiB, ...), e.g. "42.3 GiB".
+func HumanSize(n int64) string {
+	return formatSize(n < 0, absSize(n), 1024, iecUnits)
+}
+
+// HumanSizeSI formats n bytes using SI units (kB, MB, ...), e.g. "45.4 GB".
+func HumanSizeSI(n int64) string {
+	return formatSize(n < 0, absSize(n), 1000, siUnits)
+}
+
+// String formats the usage as "<used> / <total> (<percent>%)" using IEC units.
+func (u Usage) String() string {
+	return fmt.Sprintf("%s / %s (%s%%)",
+		formatSize(false, u.Used, 1024, iecUnits),
+		formatSize(false, u.Total, 1024, iecUnits),
+		trimZeroDecimal(strconv.FormatFloat(u.UsedPercent, 'f', 1, 64)),
+	)
+}
+
+func absSize(n int64) uint64 {
+	if n < 0 {
+		// negating as uint64 keeps math.MinInt64 representable
+		return -uint64(n)
+	}
+	return uint64(n)
+}
+
+func formatSize(negative bool, n uint64, base float64, units []string) string {
+	sign := ""
+	if negative {
+		sign = "-"
+	}
+
+	v := float64(n)
+	i := 0
+	for v >= base && i < len(units)-1 {
+		v /= base
+		i++
+	}
+	if i == 0 {
+		return sign + strconv.FormatUint(n, 10) + " " + units[0]
+	}
+
+	s := strconv.FormatFloat(v, 'f', 1, 64)
+	// rounding can carry into the next unit, e.g. 1023.96 KiB -> "1024.0"
+	if s == strconv.FormatFloat(base, 'f', 1, 64) && i < len(units)-1 {
+		s = "1.0"
+		i++
+	}
+	return sign + trimZeroDecimal(s) + " " + units[i]
+}
+
+func trimZeroDecimal(s string) string {
+	return strings.TrimSuffix(s, ".0")
+}
+
+// allocBlockSize is the allocation unit FitsIn assumes for the destination,
+// the common filesystem block size.
+const allocBlockSize = 4096
+
+// FitsIn reports whether the file or tree p is likely to fit into the free
+// space of the filesystem holding dst, along with the bytes a copy would need
+// and the bytes available. dst need not exist yet; the free space of its
+// nearest existing ancestor is used. The requirement rounds every file up to
+// whole 4 KiB blocks and counts one block per directory, approximating
+// allocation overhead; symlinks are not counted. It is a best-effort
+// estimate: block sizes, compression and other writers on the filesystem can
+// make the real need differ, and free space may change before the copy runs.
+func (p Path) FitsIn(dst Path) (fits bool, required, available int64, err error) {
+	err = p.Walk(func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			required += allocBlockSize
+		case d.Type().IsRegular():
+			fi, err := d.Info()
+			if err != nil {
+				return errz.E(err, "stat").With("path", path)
+			}
+			required += (fi.Size() + allocBlockSize - 1) / allocBlockSize * allocBlockSize
+		}
+		return nil
+	})
+	if err != nil {
+		return false, 0, 0, errz.E(err, "compute size").With("path", p)
+	}
+
+	u, err := dst.ExistingAncestor().Usage()
+	if err != nil {
+		return false, 0, 0, errz.E(err, "get free space").With("path", dst)
+	}
+	available = int64(min(u.Free, math.MaxInt64))
+	return required <= available, required, available, nil
+}
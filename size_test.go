@@ -0,0 +1,76 @@
+package ppath
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHumanSize(t *testing.T) {
+	tests := []struct {
+		in  int64
+		iec string
+		si  string
+	}{
+		{0, "0 B", "0 B"},
+		{1, "1 B", "1 B"},
+		{999, "999 B", "999 B"},
+		{1000, "1000 B", "1 kB"},
+		{1024, "1 KiB", "1 kB"},
+		{1536, "1.5 KiB", "1.5 kB"},
+		{1024*1024 - 1, "1 MiB", "1 MB"},
+		{100 << 30, "100 GiB", "107.4 GB"},
+		{-1536, "-1.5 KiB", "-1.5 kB"},
+		{math.MaxInt64, "8 EiB", "9.2 EB"},
+		{math.MinInt64, "-8 EiB", "-9.2 EB"},
+	}
+
+	for _, tt := range tests {
+		if got := HumanSize(tt.in); got != tt.iec {
+			t.Errorf("HumanSize(%d): expected %s, got %s", tt.in, tt.iec, got)
+		}
+		if got := HumanSizeSI(tt.in); got != tt.si {
+			t.Errorf("HumanSizeSI(%d): expected %s, got %s", tt.in, tt.si, got)
+		}
+	}
+}
+
+func TestUsageString(t *testing.T) {
+	u := Usage{Total: 100 << 30, Used: 42<<30 + 300<<20, UsedPercent: 42.29}
+	if expected, got := "42.3 GiB / 100 GiB (42.3%)", u.String(); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+
+	if expected, got := "0 B / 0 B (0%)", (Usage{}).String(); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestFitsIn(t *testing.T) {
+	tempDir := New(t.TempDir())
+	src := tempDir.Join("src")
+	if err := src.Join("a").WriteFile([]byte("a")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := src.Join("sub", "b").WriteFile(make([]byte, 5000)); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	fits, required, available, err := src.FitsIn(tempDir.Join("not", "yet", "created"))
+	if err != nil {
+		t.Fatalf("FitsIn: %v", err)
+	}
+	// Two directories of one block, a one block file and a two block file.
+	if required != 5*allocBlockSize {
+		t.Errorf("expected %d bytes required, got %d", 5*allocBlockSize, required)
+	}
+	if available <= 0 || fits != (required <= available) {
+		t.Errorf("unexpected result: fits %v, required %d, available %d", fits, required, available)
+	}
+
+	if _, required, _, err := src.Join("a").FitsIn(tempDir); err != nil || required != allocBlockSize {
+		t.Errorf("expected %d bytes for a single file, got %d, error: %v", allocBlockSize, required, err)
+	}
+	if _, _, _, err := tempDir.Join("missing").FitsIn(tempDir); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
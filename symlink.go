@@ -0,0 +1,173 @@
+package ppath
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/maa3x/errz"
+)
+
+// ErrSymlinkConflict is returned by MkdirAllResolved when a component of the
+// path is a symlink that does not lead to a directory.
+var ErrSymlinkConflict = errors.New("symlink component conflicts with directory")
+
+// Relocate moves p to dst and leaves a symlink at p pointing to the new
+// location, so existing references to p keep working. If dst is a directory,
+// p is moved into it under its base name. If p is already a symlink, the file
+// it points to is moved and the link is repointed. The link is created under a
+// temporary name and renamed over p, so p is never observed missing when it
+// was a symlink before.
+func (p Path) Relocate(dst Path) error {
+	src := p
+	if p.IsSymlink() {
+		target, err := filepath.EvalSymlinks(string(p))
+		if err != nil {
+			return errz.E(err, "resolve symlink")
+		}
+		src = Path(target)
+	}
+	if dst.IsDir() {
+		dst = dst.JoinPath(p.Base())
+	}
+
+	if err := src.Move(dst); err != nil {
+		return errz.E(err, "move file")
+	}
+
+	abs, err := dst.Abs()
+	if err != nil {
+		return errz.E(err, "resolve destination")
+	}
+	if err := p.replaceSymlink(abs.String()); err != nil {
+		return errz.E(err, "create symlink")
+	}
+	return nil
+}
+
+// UpdateLatest points the symlink at p, such as "releases/current", to target,
+// which must exist. The link stores target relative to p's directory, so the
+// tree stays valid when moved as a whole. The new link is created under a
+// temporary name and renamed over p, so p always resolves to either the old
+// or the new target and is never missing. p may not exist yet, but if it does
+// it must be a symlink: anything else is left alone and reported as an error.
+func (p Path) UpdateLatest(target Path) error {
+	if fi, err := os.Lstat(string(p)); err == nil && fi.Mode()&fs.ModeSymlink == 0 {
+		return errz.E("already exists but not a symlink").With("path", p)
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return errz.E(err, "stat link").With("path", p)
+	}
+	if _, err := target.Stat(); err != nil {
+		return errz.E(err, "stat target").With("target", target)
+	}
+
+	absTarget, err := target.Abs()
+	if err != nil {
+		return errz.E(err, "resolve target").With("target", target)
+	}
+	absDir, err := p.Dir().Abs()
+	if err != nil {
+		return errz.E(err, "resolve link directory").With("path", p)
+	}
+	rel, err := filepath.Rel(string(absDir), string(absTarget))
+	if err != nil {
+		return errz.E(err, "relative target").With("path", p).With("target", target)
+	}
+
+	if err := p.replaceSymlink(rel); err != nil {
+		return errz.E(err, "replace symlink").With("path", p).With("target", rel)
+	}
+	return nil
+}
+
+// MkdirAllResolved creates p and any missing parents like MkdirIfNotExist, but
+// walks the path one component at a time so that symlinked components are
+// followed to the directories they point to and only genuinely missing
+// directories are created. A component that is a dangling symlink, or a
+// symlink to something other than a directory, is never replaced or created
+// through: the call fails with an error matching ErrSymlinkConflict that
+// carries the offending "link" and its "target". p is made absolute and
+// cleaned lexically before the walk.
+func (p Path) MkdirAllResolved() error {
+	abs, err := p.Abs()
+	if err != nil {
+		return errz.E(err, "resolve path").With("path", p)
+	}
+	clean := filepath.Clean(string(abs))
+	vol := filepath.VolumeName(clean)
+
+	cur := vol + string(filepath.Separator)
+	for _, name := range strings.Split(clean[len(vol):], string(filepath.Separator)) {
+		if name == "" {
+			continue
+		}
+		next := filepath.Join(cur, name)
+		if err := mkdirResolved(next); err != nil {
+			return err
+		}
+		cur = next
+	}
+	return nil
+}
+
+// mkdirResolved ensures the single component at path is a directory or a
+// symlink leading to one, creating it if nothing exists there.
+func mkdirResolved(path string) error {
+	fi, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		err = os.Mkdir(path, defaultDirPerm())
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return errz.E(err, "create directory").With("path", path)
+		}
+		// Lost a race with another creator; check what is there now.
+		fi, err = os.Lstat(path)
+	}
+	if err != nil {
+		return errz.E(err, "stat path component").With("path", path)
+	}
+
+	if fi.Mode()&fs.ModeSymlink != 0 {
+		target, _ := os.Readlink(path)
+		fi, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return errz.E(ErrSymlinkConflict, "dangling symlink").With("link", path).With("target", target)
+		}
+		if err != nil {
+			return errz.E(err, "resolve symlink").With("link", path)
+		}
+		if !fi.IsDir() {
+			return errz.E(ErrSymlinkConflict, "symlink to non-directory").With("link", path).With("target", target)
+		}
+		return nil
+	}
+	if !fi.IsDir() {
+		return errz.E("already exists but not a directory").With("path", path)
+	}
+	return nil
+}
+
+// replaceSymlink atomically points the symlink at p to target by creating a
+// temporary link next to p and renaming it over p.
+func (p Path) replaceSymlink(target string) error {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return err
+	}
+
+	tmp := p.Dir().Join("." + p.Base().String() + "." + hex.EncodeToString(b[:]))
+	if err := os.Symlink(target, tmp.String()); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.String(), string(p)); err != nil {
+		os.Remove(tmp.String())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package ppath
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/maa3x/errz"
+)
+
+func TestRelocate(t *testing.T) {
+	tempDir := New(t.TempDir())
+	log := tempDir.Join("app.log")
+	archive := tempDir.Join("archive")
+	if err := log.WriteFile(testContent); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := archive.MkdirIfNotExist(); err != nil {
+		t.Fatalf("MkdirIfNotExist: %v", err)
+	}
+
+	// Relocating into a directory keeps the base name.
+	if err := log.Relocate(archive); err != nil {
+		t.Fatalf("Relocate: %v", err)
+	}
+	moved := archive.Join("app.log")
+	if !moved.IsRegular() {
+		t.Errorf("expected %s to be a regular file", moved)
+	}
+	if !log.IsSymlink() {
+		t.Errorf("expected %s to be a symlink", log)
+	}
+	content, err := log.ReadFile()
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(content) != string(testContent) {
+		t.Errorf("expected %s, got %s", testContent, content)
+	}
+
+	// Relocating an existing symlink moves its target and repoints the link.
+	final := tempDir.Join("old", "app-1.log")
+	if err := log.Relocate(final); err != nil {
+		t.Fatalf("Relocate: %v", err)
+	}
+	if moved.Exists() {
+		t.Errorf("expected %s to be moved", moved)
+	}
+	target, err := os.Readlink(log.String())
+	if err != nil {
+		t.Fatalf("os.Readlink: %v", err)
+	}
+	if !New(target).IsEqual(final) {
+		t.Errorf("expected link to point to %s, got %s", final, target)
+	}
+	content, err = log.ReadFile()
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(content) != string(testContent) {
+		t.Errorf("expected %s, got %s", testContent, content)
+	}
+}
+
+func TestMkdirAllResolved(t *testing.T) {
+	tempDir := New(t.TempDir())
+	shared := tempDir.Join("shared")
+	release := tempDir.Join("release")
+	if err := shared.MkdirIfNotExist(); err != nil {
+		t.Fatalf("MkdirIfNotExist: %v", err)
+	}
+	if err := release.MkdirIfNotExist(); err != nil {
+		t.Fatalf("MkdirIfNotExist: %v", err)
+	}
+	if err := os.Symlink(shared.String(), release.Join("logs").String()); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+
+	// Missing directories below a symlinked component land in its target.
+	if err := release.Join("logs", "app", "2024").MkdirAllResolved(); err != nil {
+		t.Fatalf("MkdirAllResolved: %v", err)
+	}
+	if !shared.Join("app", "2024").IsDir() {
+		t.Errorf("expected %s to be created", shared.Join("app", "2024"))
+	}
+	if !release.Join("logs").IsSymlink() {
+		t.Errorf("expected %s to remain a symlink", release.Join("logs"))
+	}
+	// Existing directories are fine.
+	if err := release.Join("logs", "app").MkdirAllResolved(); err != nil {
+		t.Errorf("MkdirAllResolved: %v", err)
+	}
+
+	dangling := release.Join("cache")
+	if err := os.Symlink(tempDir.Join("missing").String(), dangling.String()); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+	file := tempDir.Join("file.txt")
+	if err := file.WriteFile(testContent); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	toFile := release.Join("data")
+	if err := os.Symlink(file.String(), toFile.String()); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+
+	for _, link := range []Path{dangling, toFile} {
+		err := link.Join("sub").MkdirAllResolved()
+		if !errors.Is(err, ErrSymlinkConflict) {
+			t.Errorf("expected ErrSymlinkConflict for %s, got %v", link, err)
+			continue
+		}
+		var e *errz.Error
+		if errors.As(err, &e) {
+			if got := e.Meta().Get("link"); len(got) == 0 || got[0] != link.String() {
+				t.Errorf("expected link %s, got %v", link, got)
+			}
+		}
+	}
+	if tempDir.Join("missing").IsExist() {
+		t.Errorf("expected nothing to be created through the dangling symlink")
+	}
+
+	if err := file.Join("sub").MkdirAllResolved(); err == nil || errors.Is(err, ErrSymlinkConflict) {
+		t.Errorf("expected a not-a-directory error, got %v", err)
+	}
+}
+
+func TestUpdateLatest(t *testing.T) {
+	root := New(t.TempDir(), "app")
+	releases := root.Join("releases")
+	v1, v2 := releases.Join("v1"), releases.Join("v2")
+	for _, dir := range []Path{v1, v2} {
+		if err := dir.Join("VERSION").WriteFile([]byte(dir.Base())); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	current := releases.Join("current")
+
+	for _, target := range []Path{v1, v2} {
+		if err := current.UpdateLatest(target); err != nil {
+			t.Fatalf("UpdateLatest(%s): %v", target, err)
+		}
+		link, err := os.Readlink(current.String())
+		if err != nil {
+			t.Fatalf("Readlink: %v", err)
+		}
+		if link != target.Base().String() {
+			t.Errorf("expected relative target %s, got %s", target.Base(), link)
+		}
+		content, err := current.Join("VERSION").ReadFile()
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		if string(content) != target.Base().String() {
+			t.Errorf("expected %s, got %s", target.Base(), content)
+		}
+	}
+
+	// The relative link survives moving the whole tree.
+	moved := New(t.TempDir(), "moved")
+	if err := os.Rename(root.String(), moved.String()); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if content, err := moved.Join("releases", "current", "VERSION").ReadFile(); err != nil || string(content) != "v2" {
+		t.Errorf("expected v2 after moving the tree, got %q (%v)", content, err)
+	}
+
+	regular := moved.Join("releases", "v1", "VERSION")
+	if err := regular.UpdateLatest(moved.Join("releases", "v2")); err == nil {
+		t.Errorf("expected error for a regular file")
+	}
+	if content, err := regular.ReadFile(); err != nil || string(content) != "v1" {
+		t.Errorf("expected regular file to be left alone, got %q (%v)", content, err)
+	}
+	if err := moved.Join("releases", "current").UpdateLatest(moved.Join("releases", "v3")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected os.ErrNotExist for a missing target, got %v", err)
+	}
+}
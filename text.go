@@ -0,0 +1,74 @@
+package ppath
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"unicode/utf16"
+
+	"github.com/maa3x/errz"
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// HasBOM reports whether the file starts with a UTF-8, UTF-16 LE or UTF-16 BE
+// byte order mark.
+func (p Path) HasBOM() (bool, error) {
+	f, err := p.OpenHandle()
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	head := make([]byte, len(bomUTF8))
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return false, err
+	}
+	_, size := detectBOM(head[:n])
+	return size > 0, nil
+}
+
+// ReadTextFile reads the file as text and returns it as UTF-8. A UTF-8 byte
+// order mark is stripped and UTF-16 content marked by a little or big endian
+// BOM is transcoded. Without a BOM the content is returned as is.
+func (p Path) ReadTextFile() (string, error) {
+	data, err := p.ReadFile()
+	if err != nil {
+		return "", err
+	}
+
+	order, size := detectBOM(data)
+	data = data[size:]
+	if order == nil {
+		return string(data), nil
+	}
+	if len(data)%2 != 0 {
+		return "", errz.E("truncated UTF-16 content").With("path", p)
+	}
+
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+// detectBOM returns the length of the byte order mark data starts with, and
+// the byte order for UTF-16 content, which is nil for UTF-8 or no BOM.
+func detectBOM(data []byte) (binary.ByteOrder, int) {
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		return nil, len(bomUTF8)
+	case bytes.HasPrefix(data, bomUTF16LE):
+		return binary.LittleEndian, len(bomUTF16LE)
+	case bytes.HasPrefix(data, bomUTF16BE):
+		return binary.BigEndian, len(bomUTF16BE)
+	}
+	return nil, 0
+}
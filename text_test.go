@@ -0,0 +1,58 @@
+package ppath
+
+import "testing"
+
+func TestReadTextFile(t *testing.T) {
+	dir := New(t.TempDir())
+	tests := []struct {
+		name     string
+		content  []byte
+		expected string
+		bom      bool
+	}{
+		{"plain", []byte("key=värde\n"), "key=värde\n", false},
+		{"utf8 bom", []byte("\xEF\xBB\xBFkey=1"), "key=1", true},
+		{"utf16 le", []byte{0xFF, 0xFE, 'h', 0, 'i', 0, 0xE4, 0, 0x3D, 0xD8, 0x00, 0xDE}, "hiä😀", true},
+		{"utf16 be", []byte{0xFE, 0xFF, 0, 'h', 0, 'i', 0, '\n'}, "hi\n", true},
+		{"bom only", []byte{0xEF, 0xBB, 0xBF}, "", true},
+		{"short", []byte{0xEF}, "\xEF", false},
+		{"empty", nil, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := dir.Join(tt.name)
+			if err := p.WriteFile(tt.content); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+
+			text, err := p.ReadTextFile()
+			if err != nil {
+				t.Fatalf("ReadTextFile: %v", err)
+			}
+			if text != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, text)
+			}
+
+			bom, err := p.HasBOM()
+			if err != nil {
+				t.Fatalf("HasBOM: %v", err)
+			}
+			if bom != tt.bom {
+				t.Errorf("expected HasBOM %v, got %v", tt.bom, bom)
+			}
+		})
+	}
+
+	odd := dir.Join("odd")
+	if err := odd.WriteFile([]byte{0xFF, 0xFE, 'h'}); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if _, err := odd.ReadTextFile(); err == nil {
+		t.Errorf("expected error for truncated UTF-16, got nil")
+	}
+
+	if _, err := dir.Join("missing").HasBOM(); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
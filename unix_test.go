@@ -0,0 +1,87 @@
+//go:build linux || darwin
+
+package ppath
+
+import (
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestCopySparseAllocation(t *testing.T) {
+	tempDir := New(t.TempDir())
+	src := tempDir.Join("disk.img")
+	f, err := src.Create()
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	const size = 64 << 20
+	if err := f.Truncate(size); err != nil {
+		t.Fatalf("Truncate: %v", err)
+	}
+	if _, err := f.WriteAt([]byte("boot"), 0); err != nil {
+		t.Fatalf("WriteAt: %v", err)
+	}
+	f.Close()
+
+	if allocated(t, src) >= size/2 {
+		t.Skip("filesystem does not support sparse files")
+	}
+
+	dst := tempDir.Join("copy.img")
+	if err := src.CopySparse(dst); err != nil {
+		t.Fatalf("CopySparse: %v", err)
+	}
+	if n, _ := dst.Size(); n != size {
+		t.Errorf("expected size %d, got %d", size, n)
+	}
+	if n := allocated(t, dst); n >= size/2 {
+		t.Errorf("expected a sparse copy, got %d bytes allocated", n)
+	}
+}
+
+func allocated(t *testing.T, p Path) int64 {
+	t.Helper()
+	fi, err := p.Stat()
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	return fi.Sys().(*syscall.Stat_t).Blocks * 512
+}
+
+func TestAllocateReserves(t *testing.T) {
+	p := New(t.TempDir()).Join("wal")
+	const size = 8 << 20
+	err := p.Allocate(size)
+	if err != nil {
+		t.Fatalf("Allocate: %v", err)
+	}
+	if n := allocated(t, p); n < size {
+		t.Skipf("filesystem does not preallocate, got %d bytes allocated", n)
+	}
+}
+
+func TestReadFileTimeoutHung(t *testing.T) {
+	fifo := New(t.TempDir()).Join("hung.fifo")
+	if err := syscall.Mkfifo(fifo.String(), 0o600); err != nil {
+		t.Fatalf("Mkfifo: %v", err)
+	}
+	// Holding the FIFO open for writing without writing makes reads block.
+	w, err := os.OpenFile(fifo.String(), os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	defer w.Close()
+
+	start := time.Now()
+	_, err = fifo.ReadFileTimeout(50 * time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected to return after the timeout, took %v", elapsed)
+	}
+}
@@ -0,0 +1,59 @@
+//go:build linux || darwin
+
+package ppath
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	"github.com/maa3x/errz"
+)
+
+func volumeOf(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", errz.E("device id not available")
+	}
+	return strconv.FormatUint(uint64(stat.Dev), 10), nil //nolint:unconvert // Dev is int32 on darwin
+}
+
+func isCrossDevice(err error) bool {
+	return errors.Is(err, syscall.EXDEV)
+}
+
+// isMountPoint compares the device of path with that of its parent directory.
+// path must be absolute.
+func isMountPoint(path string) (bool, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return false, err
+	}
+	parent := filepath.Dir(path)
+	if parent == path {
+		return true, nil
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return false, nil
+	}
+
+	dev, err := volumeOf(path)
+	if err != nil {
+		return false, err
+	}
+	parentDev, err := volumeOf(parent)
+	if err != nil {
+		return false, err
+	}
+	return dev != parentDev, nil
+}
+
+func roots() ([]Path, error) {
+	return []Path{"/"}, nil
+}
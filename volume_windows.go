@@ -0,0 +1,64 @@
+//go:build windows
+
+package ppath
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+func volumeOf(path string) (string, error) {
+	pointer, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return "", err
+	}
+
+	buf := make([]uint16, windows.MAX_PATH+1)
+	if err := windows.GetVolumePathName(pointer, &buf[0], uint32(len(buf))); err != nil {
+		return "", err
+	}
+	return strings.ToLower(windows.UTF16ToString(buf)), nil
+}
+
+func isCrossDevice(err error) bool {
+	return errors.Is(err, windows.ERROR_NOT_SAME_DEVICE)
+}
+
+// isMountPoint reports whether path is the root of the volume it is on, which
+// covers drive roots as well as volumes mounted on a folder. path must be
+// absolute.
+func isMountPoint(path string) (bool, error) {
+	if _, err := os.Lstat(path); err != nil {
+		return false, err
+	}
+	root, err := volumeOf(path)
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(strings.TrimRight(root, `\`), strings.TrimRight(path, `\`)), nil
+}
+
+// roots lists the drive letters reported by GetLogicalDrives, skipping drives
+// that are not ready, such as an empty card reader.
+func roots() ([]Path, error) {
+	mask, err := windows.GetLogicalDrives()
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []Path
+	for i := range 26 {
+		if mask&(1<<i) == 0 {
+			continue
+		}
+		root := string(rune('A'+i)) + `:\`
+		if _, err := os.Stat(root); err != nil {
+			continue
+		}
+		paths = append(paths, Path(root))
+	}
+	return paths, nil
+}
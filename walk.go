@@ -0,0 +1,429 @@
+package ppath
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"iter"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/maa3x/errz"
+)
+
+// Stats summarizes the contents of a directory tree.
+type Stats struct {
+	Size    int64
+	Files   int
+	Dirs    int
+	ModTime time.Time
+}
+
+// WalkStats collects the total size of regular files, the number of files and
+// subdirectories, and the newest modification time under p in a single walk.
+// The root itself is not counted and symlinks are skipped so nothing is
+// counted twice. Entries that cannot be read are reported in the returned
+// error while the stats gathered from the rest of the tree are still returned.
+func (p Path) WalkStats() (Stats, error) {
+	var (
+		st   Stats
+		errs []error
+	)
+	err := p.Walk(func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d == nil && path == string(p) {
+				return err
+			}
+			errs = append(errs, errz.E(err, "walk").With("path", path))
+			return nil
+		}
+		if path == string(p) || d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			errs = append(errs, errz.E(err, "stat").With("path", path))
+			return nil
+		}
+		switch {
+		case fi.IsDir():
+			st.Dirs++
+		case fi.Mode().IsRegular():
+			st.Files++
+			st.Size += fi.Size()
+		}
+		if fi.ModTime().After(st.ModTime) {
+			st.ModTime = fi.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		return Stats{}, errz.E(err, "walk directory")
+	}
+	return st, errz.Join(errs...)
+}
+
+// WalkPath walks the tree rooted at p like Walk, but passes each path to fn as
+// a Path instead of a string.
+func (p Path) WalkPath(fn func(p Path, d fs.DirEntry, err error) error) error {
+	return p.Walk(func(path string, d fs.DirEntry, err error) error {
+		return fn(Path(path), d, err)
+	})
+}
+
+// WalkEntries walks the tree rooted at p like Walk, passing the full path of
+// each entry as a Path. Any error encountered while walking stops the walk and
+// is returned. fn may return fs.SkipDir or fs.SkipAll as with Walk.
+func (p Path) WalkEntries(fn func(p Path, d fs.DirEntry) error) error {
+	return p.Walk(func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		return fn(Path(path), d)
+	})
+}
+
+// WalkSorted walks the tree rooted at p like Walk, but guarantees the visiting
+// order: a directory is visited before its children, and children are visited
+// in byte-wise order of their names. Names never contain a separator, so the
+// order is the same on every platform and matches sorting the slash-separated
+// relative paths segment by segment. Symlinks are not followed.
+//
+// Each directory's entries are read fully and held in memory until all of its
+// children are visited, so memory use grows with the size of the directories
+// along the current path rather than with the size of the whole tree.
+func (p Path) WalkSorted(fn fs.WalkDirFunc) error {
+	info, err := os.Lstat(string(p))
+	if err != nil {
+		err = fn(string(p), nil, err)
+	} else {
+		err = walkSorted(string(p), fs.FileInfoToDirEntry(info), fn)
+	}
+	if errors.Is(err, fs.SkipDir) || errors.Is(err, fs.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+func walkSorted(path string, d fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(path, d, nil); err != nil || !d.IsDir() {
+		if errors.Is(err, fs.SkipDir) && d.IsDir() {
+			return nil
+		}
+		return err
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		if err := fn(path, d, err); err != nil {
+			if errors.Is(err, fs.SkipDir) {
+				return nil
+			}
+			return err
+		}
+	}
+
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	for _, e := range entries {
+		if err := walkSorted(filepath.Join(path, e.Name()), e, fn); err != nil {
+			if errors.Is(err, fs.SkipDir) {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// ErrSymlinkCycle is passed to the function given to WalkFollow for a symlink
+// that leads back to a directory the walk is already inside.
+var ErrSymlinkCycle = errors.New("symlink cycle")
+
+// WalkFollow walks the tree rooted at p like WalkSorted, but follows symlinks:
+// a link to a directory is descended into, and the entry passed to fn for a
+// link describes its target while keeping the link's name. Links that cannot
+// be resolved are passed as they are. Each directory is identified by its
+// FileID, and one that is already on the path from p to the current entry is
+// not entered again. Instead fn is called for it with an error matching
+// ErrSymlinkCycle that carries the "link", its "target" and the "ancestor"
+// path it loops back to as metadata. Returning nil from fn skips that
+// directory; returning the error aborts the walk with it. Directories reached
+// through different links that do not form a cycle are walked each time.
+func (p Path) WalkFollow(fn fs.WalkDirFunc) error {
+	info, err := os.Stat(string(p))
+	if err != nil {
+		err = fn(string(p), nil, err)
+	} else {
+		err = walkFollow(string(p), fs.FileInfoToDirEntry(info), make(map[FileID]string), fn)
+	}
+	if errors.Is(err, fs.SkipDir) || errors.Is(err, fs.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+func walkFollow(path string, d fs.DirEntry, ancestors map[FileID]string, fn fs.WalkDirFunc) error {
+	if !d.IsDir() {
+		return fn(path, d, nil)
+	}
+
+	skip := func(err error) error {
+		if errors.Is(err, fs.SkipDir) {
+			return nil
+		}
+		return err
+	}
+	id, err := dirID(path)
+	if err != nil {
+		return skip(fn(path, d, err))
+	}
+	if ancestor, ok := ancestors[id]; ok {
+		target, _ := os.Readlink(path)
+		cycle := errz.E(ErrSymlinkCycle).With("link", path).With("target", target).With("ancestor", ancestor)
+		return skip(fn(path, d, cycle))
+	}
+	if err := fn(path, d, nil); err != nil {
+		return skip(err)
+	}
+
+	ancestors[id] = path
+	defer delete(ancestors, id)
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		if err := fn(path, d, err); err != nil {
+			return skip(err)
+		}
+	}
+	for _, e := range entries {
+		child := filepath.Join(path, e.Name())
+		if e.Type()&fs.ModeSymlink != 0 {
+			if fi, err := os.Stat(child); err == nil {
+				e = fs.FileInfoToDirEntry(fi)
+			}
+		}
+		if err := walkFollow(child, e, ancestors, fn); err != nil {
+			if errors.Is(err, fs.SkipDir) {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// dirID returns the FileID of the directory at path, following symlinks.
+func dirID(path string) (FileID, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return FileID{}, err
+	}
+	if id, ok := infoID(fi); ok {
+		return id, nil
+	}
+	return fileID(path)
+}
+
+// DeleteMatching removes every entry under p whose slash-separated path
+// relative to p matches pattern as in WalkMatch, so "*" never crosses a slash
+// and "**" spans directories. A pattern without a slash is matched against the
+// entry's name instead, so "*.tmp" matches at any depth. A matching directory
+// is removed with its contents, and symlinks are removed themselves and never
+// followed. The root itself is never removed. Removal is best-effort: the
+// number of entries removed is returned together with an error joining every
+// entry that could not be read or removed.
+func (p Path) DeleteMatching(pattern string) (int, error) {
+	if err := validPattern(pattern); err != nil {
+		return 0, errz.E(err, "invalid pattern").With("pattern", pattern)
+	}
+	byName := !strings.Contains(pattern, "/")
+
+	return p.deleteWhere(func(path string, d fs.DirEntry) (bool, error) {
+		name := d.Name()
+		if !byName {
+			rel, err := filepath.Rel(string(p), path)
+			if err != nil {
+				return false, err
+			}
+			name = filepath.ToSlash(rel)
+		}
+		return matchPath(pattern, name), nil
+	})
+}
+
+// DeleteOlderThan removes every non-directory entry under p whose modification
+// time is more than d ago. Symlinks are judged by their own modification time
+// and are never followed; directories are left in place even when they become
+// empty. Removal is best-effort as with DeleteMatching.
+func (p Path) DeleteOlderThan(d time.Duration) (int, error) {
+	cutoff := time.Now().Add(-d)
+	return p.deleteWhere(func(_ string, e fs.DirEntry) (bool, error) {
+		if e.IsDir() {
+			return false, nil
+		}
+		fi, err := e.Info()
+		if err != nil {
+			return false, err
+		}
+		return fi.ModTime().Before(cutoff), nil
+	})
+}
+
+func (p Path) deleteWhere(match func(path string, d fs.DirEntry) (bool, error)) (int, error) {
+	var (
+		n    int
+		errs []error
+	)
+	err := p.Walk(func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d == nil && path == string(p) {
+				return err
+			}
+			errs = append(errs, errz.E(err, "walk").With("path", path))
+			return nil
+		}
+		if path == string(p) {
+			return nil
+		}
+
+		ok, err := match(path, d)
+		if err != nil {
+			errs = append(errs, errz.E(err, "stat").With("path", path))
+			return nil
+		}
+		if !ok {
+			return nil
+		}
+		if err := filesystem().RemoveAll(path); err != nil {
+			errs = append(errs, errz.E(err, "remove").With("path", path))
+			return nil
+		}
+		n++
+		if d.IsDir() {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return n, errz.E(err, "walk directory")
+	}
+	return n, errz.Join(errs...)
+}
+
+// WalkMatch walks the tree rooted at p and calls fn for every entry whose
+// slash-separated path relative to p matches pattern. Patterns are matched
+// segment by segment as with path.Match, and a "**" segment matches any number
+// of directories, so "*.proto" only matches at the top level while
+// "**/*.proto" matches at any depth. The root itself is never passed to fn
+// and symlinks are not followed. fn may return fs.SkipDir for a directory to
+// skip its contents; any other error stops the walk and is returned, as are
+// errors encountered while walking.
+func (p Path) WalkMatch(pattern string, fn func(Path) error) error {
+	if err := validPattern(pattern); err != nil {
+		return errz.E(err, "invalid pattern").With("pattern", pattern)
+	}
+
+	return p.Walk(func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == string(p) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(string(p), path)
+		if err != nil {
+			return err
+		}
+		if !matchPath(pattern, filepath.ToSlash(rel)) {
+			return nil
+		}
+		return fn(Path(path))
+	})
+}
+
+// WalkSameDevice walks the tree rooted at p like Walk, but leaves out
+// directories that reside on a different filesystem than p, such as mount
+// points of other volumes, together with everything below them, like
+// find -xdev. fn is not called for such directories. If the device of a
+// directory cannot be determined, fn is called with the error, and may return
+// fs.SkipDir to leave it out or nil to descend into it anyway.
+func (p Path) WalkSameDevice(fn fs.WalkDirFunc) error {
+	dev, err := volumeOf(string(p))
+	if err != nil {
+		return fn(string(p), nil, err)
+	}
+
+	return p.Walk(func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == string(p) || !d.IsDir() {
+			return fn(path, d, err)
+		}
+
+		other, err := volumeOf(path)
+		if err != nil {
+			return fn(path, d, errz.E(err, "get device").With("path", path))
+		}
+		if other != dev {
+			return fs.SkipDir
+		}
+		return fn(path, d, nil)
+	})
+}
+
+// progressInterval is the minimum time between two WalkProgress reports.
+const progressInterval = 100 * time.Millisecond
+
+// WalkProgress walks the tree like Walk and calls progress with the number of
+// entries scanned so far. Reports are throttled to one per progressInterval,
+// with a final report once the walk ends, so progress can drive a UI directly.
+func (p Path) WalkProgress(fn fs.WalkDirFunc, progress func(scanned int)) error {
+	scanned := 0
+	last := time.Now()
+	err := p.Walk(func(path string, d fs.DirEntry, err error) error {
+		scanned++
+		if now := time.Now(); now.Sub(last) >= progressInterval {
+			last = now
+			progress(scanned)
+		}
+		return fn(path, d, err)
+	})
+	progress(scanned)
+	return err
+}
+
+// WalkSeqContext returns an iterator over every entry below p that is not a
+// directory, in the order of Walk. ctx is checked before each entry; once it
+// is done, its error is yielded as the final value. An entry that cannot be
+// read is yielded with its error, and the walk goes on unless the consumer
+// stops; a directory that cannot be read is not descended into.
+func (p Path) WalkSeqContext(ctx context.Context) iter.Seq2[Path, error] {
+	return func(yield func(Path, error) bool) {
+		err := p.Walk(func(path string, d fs.DirEntry, err error) error {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			if err != nil {
+				if !yield(Path(path), err) {
+					return fs.SkipAll
+				}
+				return nil
+			}
+			if d.IsDir() {
+				return nil
+			}
+			if !yield(Path(path), nil) {
+				return fs.SkipAll
+			}
+			return nil
+		})
+		if err != nil {
+			yield("", err)
+		}
+	}
+}
@@ -0,0 +1,527 @@
+package ppath
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/maa3x/errz"
+)
+
+func TestWalkStats(t *testing.T) {
+	root := New(t.TempDir())
+	if err := root.Join("a.txt").WriteFile([]byte("abc")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := root.Join("sub", "b.txt").WriteFile([]byte("de")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := root.Join("sub", "empty").MkdirIfNotExist(); err != nil {
+		t.Fatalf("MkdirIfNotExist: %v", err)
+	}
+	if err := os.Symlink(root.Join("a.txt").String(), root.Join("link").String()); err != nil {
+		t.Fatalf("os.Symlink: %v", err)
+	}
+	newest := time.Now().Add(time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(root.Join("sub", "b.txt").String(), newest, newest); err != nil {
+		t.Fatalf("os.Chtimes: %v", err)
+	}
+
+	st, err := root.WalkStats()
+	if err != nil {
+		t.Fatalf("WalkStats: %v", err)
+	}
+	if st.Size != 5 {
+		t.Errorf("expected size 5, got %d", st.Size)
+	}
+	if st.Files != 2 {
+		t.Errorf("expected 2 files, got %d", st.Files)
+	}
+	if st.Dirs != 2 {
+		t.Errorf("expected 2 directories, got %d", st.Dirs)
+	}
+	if !st.ModTime.Equal(newest) {
+		t.Errorf("expected newest mtime %v, got %v", newest, st.ModTime)
+	}
+
+	if _, err := root.Join("missing").WalkStats(); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
+func TestWalkSorted(t *testing.T) {
+	root := New(t.TempDir())
+	for _, name := range []string{"b.txt", "a-c.txt", "a/z.txt", "a/b/c.txt", "skip/x.txt", "B.txt"} {
+		if err := root.Join(filepath.FromSlash(name)).WriteFile(testContent); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	var visited []string
+	err := root.WalkSorted(func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == "skip" {
+			return fs.SkipDir
+		}
+		rel, err := Path(path).Rel(root)
+		if err != nil {
+			return err
+		}
+		visited = append(visited, filepath.ToSlash(rel.String()))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkSorted: %v", err)
+	}
+
+	expected := []string{".", "B.txt", "a", "a/b", "a/b/c.txt", "a/z.txt", "a-c.txt", "b.txt"}
+	if !slices.Equal(visited, expected) {
+		t.Errorf("expected %v, got %v", expected, visited)
+	}
+
+	if err := root.Join("missing").WalkSorted(func(string, fs.DirEntry, error) error { return nil }); err != nil {
+		t.Errorf("expected callback's nil error to be returned, got %v", err)
+	}
+}
+
+func TestWalkEntries(t *testing.T) {
+	root := New(t.TempDir())
+	for _, name := range []string{"a.txt", "skip/b.txt", "sub/c.txt"} {
+		if err := root.Join(filepath.FromSlash(name)).WriteFile(testContent); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	var visited []Path
+	err := root.WalkEntries(func(p Path, d fs.DirEntry) error {
+		if d.IsDir() && d.Name() == "skip" {
+			return fs.SkipDir
+		}
+		visited = append(visited, p)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkEntries: %v", err)
+	}
+
+	expected := []Path{root, root.Join("a.txt"), root.Join("sub"), root.Join("sub", "c.txt")}
+	if !slices.Equal(visited, expected) {
+		t.Errorf("expected %v, got %v", expected, visited)
+	}
+
+	if err := root.Join("missing").WalkEntries(func(Path, fs.DirEntry) error { return nil }); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
+func TestWalkPath(t *testing.T) {
+	root := New(t.TempDir())
+	if err := root.Join("sub", "a.txt").WriteFile(testContent); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	var visited []Path
+	err := root.WalkPath(func(p Path, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		visited = append(visited, p)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkPath: %v", err)
+	}
+
+	expected := []Path{root, root.Join("sub"), root.Join("sub", "a.txt")}
+	if !slices.Equal(visited, expected) {
+		t.Errorf("expected %v, got %v", expected, visited)
+	}
+
+	var gotErr error
+	_ = root.Join("missing").WalkPath(func(p Path, d fs.DirEntry, err error) error {
+		gotErr = err
+		return nil
+	})
+	if gotErr == nil {
+		t.Errorf("expected error to be passed to the callback, got nil")
+	}
+}
+
+func TestDeleteMatching(t *testing.T) {
+	root := New(t.TempDir())
+	outside := New(t.TempDir())
+	for _, name := range []string{"a.tmp", "keep.txt", "sub/b.tmp", "sub/keep.txt", "cache.tmp/inner.txt"} {
+		if err := root.Join(name).WriteFile([]byte("x")); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	if err := outside.Join("c.tmp").WriteFile([]byte("x")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.Symlink(outside.String(), root.Join("ext").String()); err != nil {
+		t.Fatalf("os.Symlink: %v", err)
+	}
+
+	n, err := root.DeleteMatching("*.tmp")
+	if err != nil {
+		t.Fatalf("DeleteMatching: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("expected 3 removed, got %d", n)
+	}
+	for _, name := range []string{"a.tmp", "sub/b.tmp", "cache.tmp"} {
+		if root.Join(name).LExists() {
+			t.Errorf("expected %s to be removed", name)
+		}
+	}
+	for _, name := range []string{"keep.txt", "sub/keep.txt", "ext"} {
+		if !root.Join(name).LExists() {
+			t.Errorf("expected %s to be kept", name)
+		}
+	}
+	if !outside.Join("c.tmp").Exists() {
+		t.Errorf("expected symlink target to be left alone")
+	}
+
+	n, err = root.DeleteMatching("sub/*")
+	if err != nil || n != 1 || root.Join("sub", "keep.txt").Exists() {
+		t.Errorf("expected sub/keep.txt to be removed, got %d, error: %v", n, err)
+	}
+	if !root.Join("keep.txt").Exists() {
+		t.Errorf("expected keep.txt to be kept")
+	}
+
+	// "*" stops at a slash, so nested entries need "**".
+	for _, name := range []string{"logs/a.tmp", "logs/deep/b.tmp"} {
+		if err := root.Join(name).WriteFile([]byte("x")); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	n, err = root.DeleteMatching("logs/*.tmp")
+	if err != nil || n != 1 || !root.Join("logs", "deep", "b.tmp").Exists() {
+		t.Errorf("expected only logs/a.tmp to be removed, got %d, error: %v", n, err)
+	}
+	n, err = root.DeleteMatching("logs/**/*.tmp")
+	if err != nil || n != 1 || root.Join("logs", "deep", "b.tmp").Exists() {
+		t.Errorf("expected logs/deep/b.tmp to be removed, got %d, error: %v", n, err)
+	}
+
+	if _, err := root.DeleteMatching("["); err == nil {
+		t.Errorf("expected error for bad pattern, got nil")
+	}
+}
+
+func TestDeleteOlderThan(t *testing.T) {
+	root := New(t.TempDir())
+	old := time.Now().Add(-48 * time.Hour)
+	for _, name := range []string{"old.txt", "new.txt", "sub/old.txt"} {
+		if err := root.Join(name).WriteFile([]byte("x")); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	for _, name := range []string{"old.txt", "sub/old.txt", "sub"} {
+		if err := os.Chtimes(root.Join(name).String(), old, old); err != nil {
+			t.Fatalf("os.Chtimes: %v", err)
+		}
+	}
+
+	n, err := root.DeleteOlderThan(24 * time.Hour)
+	if err != nil {
+		t.Fatalf("DeleteOlderThan: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 removed, got %d", n)
+	}
+	if root.Join("old.txt").Exists() || root.Join("sub", "old.txt").Exists() {
+		t.Errorf("expected old files to be removed")
+	}
+	if !root.Join("new.txt").Exists() || !root.Join("sub").IsDir() {
+		t.Errorf("expected new file and directory to be kept")
+	}
+
+	if _, err := root.Join("missing").DeleteOlderThan(time.Hour); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
+func TestWalkMatch(t *testing.T) {
+	root := New(t.TempDir())
+	for _, name := range []string{"a.proto", "b.txt", "api/v1/c.proto", "api/d.proto", "vendor/x/e.proto"} {
+		if err := root.Join(name).WriteFile(nil); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	collect := func(pattern string) ([]string, error) {
+		var got []string
+		err := root.WalkMatch(pattern, func(p Path) error {
+			if p.Base() == "vendor" {
+				return fs.SkipDir
+			}
+			rel, err := p.Rel(root)
+			got = append(got, filepath.ToSlash(rel.String()))
+			return err
+		})
+		return got, err
+	}
+
+	got, err := collect("**/*.proto")
+	if err != nil {
+		t.Fatalf("WalkMatch: %v", err)
+	}
+	if expected := []string{"a.proto", "api/d.proto", "api/v1/c.proto", "vendor/x/e.proto"}; !slices.Equal(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	got, err = collect("*")
+	if err != nil {
+		t.Fatalf("WalkMatch: %v", err)
+	}
+	if expected := []string{"a.proto", "api", "b.txt"}; !slices.Equal(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	got, err = collect("**")
+	if err != nil {
+		t.Fatalf("WalkMatch: %v", err)
+	}
+	if slices.Contains(got, "vendor/x/e.proto") {
+		t.Errorf("expected SkipDir to skip the vendor directory, got %v", got)
+	}
+
+	if err := root.WalkMatch("[", func(Path) error { return nil }); err == nil {
+		t.Errorf("expected error for bad pattern, got nil")
+	}
+}
+
+func TestWalkSameDevice(t *testing.T) {
+	root := New(t.TempDir())
+	for _, name := range []string{"a.txt", "sub/b.txt"} {
+		if err := root.Join(name).WriteFile(nil); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	var walked, same []string
+	if err := root.Walk(func(path string, d fs.DirEntry, err error) error {
+		walked = append(walked, path)
+		return err
+	}); err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	if err := root.WalkSameDevice(func(path string, d fs.DirEntry, err error) error {
+		same = append(same, path)
+		return err
+	}); err != nil {
+		t.Fatalf("WalkSameDevice: %v", err)
+	}
+	if !slices.Equal(walked, same) {
+		t.Errorf("expected %v, got %v", walked, same)
+	}
+
+	// /proc is its own filesystem on Linux, so it is pruned when walking /
+	// unless / itself is a procfs.
+	if runtime.GOOS == "linux" {
+		if ok, err := New("/proc").IsMountPoint(); err != nil || !ok {
+			t.Skip("/proc is not a mount point")
+		}
+		var top []string
+		err := New("/").WalkSameDevice(func(path string, d fs.DirEntry, err error) error {
+			if path == "/" {
+				return err
+			}
+			top = append(top, path)
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("WalkSameDevice: %v", err)
+		}
+		if slices.Contains(top, "/proc") {
+			t.Errorf("expected /proc to be pruned, got %v", top)
+		}
+	}
+}
+
+func TestWalkProgress(t *testing.T) {
+	root := New(t.TempDir())
+	for _, name := range []string{"a.txt", "b.txt", "sub/c.txt", "sub/d.txt"} {
+		if err := root.Join(name).WriteFile(nil); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	visited := 0
+	var reports []int
+	err := root.WalkProgress(func(path string, d fs.DirEntry, err error) error {
+		visited++
+		if visited == 3 {
+			time.Sleep(progressInterval + 10*time.Millisecond)
+		}
+		return err
+	}, func(scanned int) {
+		reports = append(reports, scanned)
+	})
+	if err != nil {
+		t.Fatalf("WalkProgress: %v", err)
+	}
+	if visited != 6 {
+		t.Errorf("expected 6 entries, got %d", visited)
+	}
+	if len(reports) < 2 || len(reports) >= visited {
+		t.Errorf("expected throttled reports, got %v", reports)
+	}
+	if !slices.IsSorted(reports) || reports[len(reports)-1] != visited {
+		t.Errorf("expected increasing reports ending at %d, got %v", visited, reports)
+	}
+
+	reports = nil
+	err = root.WalkProgress(func(path string, d fs.DirEntry, err error) error {
+		if path == string(root.Join("sub")) {
+			return fs.SkipAll
+		}
+		return err
+	}, func(scanned int) {
+		reports = append(reports, scanned)
+	})
+	if err != nil {
+		t.Fatalf("WalkProgress: %v", err)
+	}
+	if !slices.Equal(reports, []int{4}) {
+		t.Errorf("expected [4], got %v", reports)
+	}
+}
+
+func TestWalkSeqContext(t *testing.T) {
+	root := New(t.TempDir())
+	for _, name := range []string{"a.txt", "sub/b.txt", "sub/deep/c.txt"} {
+		if err := root.Join(name).WriteFile(nil); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	var files []Path
+	for p, err := range root.WalkSeqContext(context.Background()) {
+		if err != nil {
+			t.Fatalf("WalkSeqContext: %v", err)
+		}
+		files = append(files, p)
+	}
+	expected := []Path{root.Join("a.txt"), root.Join("sub", "b.txt"), root.Join("sub", "deep", "c.txt")}
+	if !slices.Equal(files, expected) {
+		t.Errorf("expected %v, got %v", expected, files)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var seen int
+	var last error
+	for _, err := range root.WalkSeqContext(ctx) {
+		if err != nil {
+			last = err
+			continue
+		}
+		seen++
+		cancel()
+	}
+	if seen != 1 || !errors.Is(last, context.Canceled) {
+		t.Errorf("expected 1 file and context.Canceled, got %d, error: %v", seen, last)
+	}
+
+	count := 0
+	for range root.WalkSeqContext(context.Background()) {
+		count++
+		break
+	}
+	if count != 1 {
+		t.Errorf("expected early exit after 1 file, got %d", count)
+	}
+
+	var gotErr error
+	for _, err := range root.Join("missing").WalkSeqContext(context.Background()) {
+		gotErr = err
+	}
+	if gotErr == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
+func TestWalkFollow(t *testing.T) {
+	root := New(t.TempDir())
+	for _, name := range []string{"a/file.txt", "shared/data.txt"} {
+		if err := root.Join(name).WriteFile(nil); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	links := map[string]string{
+		"a/up":         "..",
+		"a/to-shared":  "../shared",
+		"a/to-file":    "file.txt",
+		"a/dangling":   "missing",
+		"shared/again": "../shared",
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, root.Join(link).String()); err != nil {
+			t.Skipf("os.Symlink: %v", err)
+		}
+	}
+
+	var visited []string
+	cycles := make(map[string]string)
+	err := root.WalkFollow(func(path string, d fs.DirEntry, err error) error {
+		rel, _ := filepath.Rel(root.String(), path)
+		rel = filepath.ToSlash(rel)
+		if errors.Is(err, ErrSymlinkCycle) {
+			var e *errz.Error
+			if !errors.As(err, &e) {
+				t.Fatalf("expected an *errz.Error, got %T", err)
+			}
+			ancestor, _ := filepath.Rel(root.String(), fmt.Sprint(e.Meta().Get("ancestor")[0]))
+			target := fmt.Sprint(e.Meta().Get("target")[0])
+			cycles[rel] = filepath.ToSlash(ancestor) + " via " + target
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		visited = append(visited, rel)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkFollow: %v", err)
+	}
+
+	expected := []string{
+		".", "a", "a/dangling", "a/file.txt", "a/to-file",
+		"a/to-shared", "a/to-shared/data.txt",
+		"shared", "shared/data.txt",
+	}
+	if !slices.Equal(visited, expected) {
+		t.Errorf("expected %v, got %v", expected, visited)
+	}
+	expectedCycles := map[string]string{
+		"a/up":              ". via ..",
+		"a/to-shared/again": "a/to-shared via ../shared",
+		"shared/again":      "shared via ../shared",
+	}
+	if !maps.Equal(cycles, expectedCycles) {
+		t.Errorf("expected cycles %v, got %v", expectedCycles, cycles)
+	}
+
+	err = root.WalkFollow(func(path string, d fs.DirEntry, err error) error { return err })
+	if !errors.Is(err, ErrSymlinkCycle) {
+		t.Errorf("expected ErrSymlinkCycle to abort the walk, got %v", err)
+	}
+}
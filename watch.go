@@ -0,0 +1,242 @@
+package ppath
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/maa3x/errz"
+)
+
+// EventOp describes what happened to a watched path.
+type EventOp int
+
+const (
+	// EventCreate reports a path that appeared.
+	EventCreate EventOp = iota + 1
+	// EventModify reports a file whose content or identity changed.
+	EventModify
+	// EventDelete reports a path that disappeared.
+	EventDelete
+)
+
+func (op EventOp) String() string {
+	switch op {
+	case EventCreate:
+		return "create"
+	case EventModify:
+		return "modify"
+	case EventDelete:
+		return "delete"
+	}
+	return "unknown"
+}
+
+// Event is a change to a watched path.
+type Event struct {
+	Path Path
+	Op   EventOp
+}
+
+// fileState is what a polling watcher remembers about a path between scans.
+type fileState struct {
+	size  int64
+	mtime time.Time
+	mode  fs.FileMode
+	id    FileID
+}
+
+// WatchPoll watches p by scanning it every interval and comparing the result
+// with the previous scan, for filesystems where change notifications are not
+// delivered, such as network mounts. A directory is watched recursively
+// without following symlinks. A path that appears or disappears is reported
+// with EventCreate or EventDelete; a file whose size, modification time, type
+// or file identity changed is reported with EventModify. Directories only get
+// create and delete events. Changes that are undone before the next scan are
+// not seen, and events of one scan are sent sorted by path. The returned
+// channel is closed once ctx is done. It fails if interval is not positive or
+// p cannot be scanned initially.
+func (p Path) WatchPoll(ctx context.Context, interval time.Duration) (<-chan Event, error) {
+	if interval <= 0 {
+		return nil, errz.E("interval must be positive").With("interval", interval)
+	}
+	prev, err := p.scan()
+	if err != nil {
+		return nil, errz.E(err, "scan").With("path", p)
+	}
+
+	events := make(chan Event, 64)
+	go func() {
+		defer close(events)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			// A path that cannot be scanned, for example because it was
+			// removed, is treated as empty so its entries are reported deleted.
+			next, _ := p.scan()
+			for _, e := range diffScans(prev, next) {
+				select {
+				case events <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+			prev = next
+		}
+	}()
+	return events, nil
+}
+
+// scan records the state of p and, for a directory, everything below it.
+// Entries that vanish while walking are left out.
+func (p Path) scan() (map[Path]fileState, error) {
+	states := make(map[Path]fileState)
+	err := p.Walk(func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == string(p) {
+				return err
+			}
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		id, _ := infoID(fi)
+		states[Path(path)] = fileState{size: fi.Size(), mtime: fi.ModTime(), mode: fi.Mode(), id: id}
+		return nil
+	})
+	return states, err
+}
+
+// diffScans returns the events that turn prev into next, sorted by path.
+func diffScans(prev, next map[Path]fileState) []Event {
+	var events []Event
+	for path, n := range next {
+		o, ok := prev[path]
+		switch {
+		case !ok:
+			events = append(events, Event{Path: path, Op: EventCreate})
+		case n.mode.Type() != o.mode.Type():
+			events = append(events, Event{Path: path, Op: EventModify})
+		case n.mode.IsDir():
+		case n.size != o.size || !n.mtime.Equal(o.mtime) || n.id != o.id:
+			events = append(events, Event{Path: path, Op: EventModify})
+		}
+	}
+	for path := range prev {
+		if _, ok := next[path]; !ok {
+			events = append(events, Event{Path: path, Op: EventDelete})
+		}
+	}
+	slices.SortFunc(events, func(a, b Event) int { return a.Path.Compare(b.Path) })
+	return events
+}
+
+// WatchFileOptions configures WatchFile.
+type WatchFileOptions struct {
+	// Initial sends a signal right away, so the first load can go through the
+	// same path as later reloads.
+	Initial bool
+	// Interval is how often the file is checked. Zero means 100ms.
+	Interval time.Duration
+}
+
+// WatchFileOption configures WatchFile.
+type WatchFileOption func(*WatchFileOptions)
+
+// WatchInitial makes WatchFile send a signal right away.
+func WatchInitial() WatchFileOption {
+	return func(o *WatchFileOptions) { o.Initial = true }
+}
+
+// WatchInterval sets how often WatchFile checks the file.
+func WatchInterval(d time.Duration) WatchFileOption {
+	return func(o *WatchFileOptions) { o.Interval = d }
+}
+
+// WatchFile watches the single file p by polling it and sends a signal once
+// its content has changed, that is its size, modification time or file
+// identity. A signal is only sent after the file has stayed unchanged for
+// debounce, so a burst of writes, such as an editor saving in several steps,
+// yields one signal. Because the path is checked rather than an open file, a
+// save that renames a new file over p is seen as a change and the new file is
+// watched from then on; the file disappearing counts as a change too. Signals
+// are coalesced while the receiver is busy. The returned channel is closed
+// once ctx is done. It fails if p is not an existing file.
+func (p Path) WatchFile(ctx context.Context, debounce time.Duration, opts ...WatchFileOption) (<-chan struct{}, error) {
+	o := WatchFileOptions{Interval: 100 * time.Millisecond}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if debounce < 0 || o.Interval <= 0 {
+		return nil, errz.E("debounce must not be negative and interval must be positive").
+			With("debounce", debounce).With("interval", o.Interval)
+	}
+	prev, err := p.fileState()
+	if err != nil {
+		return nil, errz.E(err, "stat").With("path", p)
+	}
+	if prev.mode.IsDir() {
+		return nil, errz.E("not a file").With("path", p)
+	}
+
+	signals := make(chan struct{}, 1)
+	if o.Initial {
+		signals <- struct{}{}
+	}
+	go func() {
+		defer close(signals)
+		ticker := time.NewTicker(o.Interval)
+		defer ticker.Stop()
+
+		var changed time.Time
+		pending := false
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				// A missing file has the zero state, so its removal and its
+				// reappearance are changes as well.
+				next, _ := p.fileState()
+				if !next.equal(prev) {
+					prev, changed, pending = next, now, true
+				}
+				if pending && now.Sub(changed) >= debounce {
+					pending = false
+					select {
+					case signals <- struct{}{}:
+					default:
+					}
+				}
+			}
+		}
+	}()
+	return signals, nil
+}
+
+func (s fileState) equal(o fileState) bool {
+	return s.size == o.size && s.mtime.Equal(o.mtime) && s.mode == o.mode && s.id == o.id
+}
+
+func (p Path) fileState() (fileState, error) {
+	fi, err := os.Stat(string(p))
+	if err != nil {
+		return fileState{}, err
+	}
+	id, ok := infoID(fi)
+	if !ok {
+		id, _ = fileID(string(p))
+	}
+	return fileState{size: fi.Size(), mtime: fi.ModTime(), mode: fi.Mode(), id: id}, nil
+}
@@ -0,0 +1,181 @@
+package ppath
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffScans(t *testing.T) {
+	now := time.Now()
+	prev := map[Path]fileState{
+		"root":         {mode: fs.ModeDir | 0o755},
+		"root/same":    {size: 1, mtime: now},
+		"root/grown":   {size: 1, mtime: now},
+		"root/touched": {size: 1, mtime: now},
+		"root/swapped": {size: 1, mtime: now, id: FileID{Index: 1}},
+		"root/gone":    {size: 1, mtime: now},
+	}
+	next := map[Path]fileState{
+		"root":         {mode: fs.ModeDir | 0o755, mtime: now},
+		"root/same":    {size: 1, mtime: now},
+		"root/grown":   {size: 2, mtime: now},
+		"root/touched": {size: 1, mtime: now.Add(time.Second)},
+		"root/swapped": {size: 1, mtime: now, id: FileID{Index: 2}},
+		"root/new":     {size: 1, mtime: now},
+	}
+
+	expected := []Event{
+		{"root/gone", EventDelete},
+		{"root/grown", EventModify},
+		{"root/new", EventCreate},
+		{"root/swapped", EventModify},
+		{"root/touched", EventModify},
+	}
+	if got := diffScans(prev, next); !slices.Equal(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestWatchPoll(t *testing.T) {
+	dir := New(t.TempDir())
+	file := dir.Join("a.txt")
+	if err := file.WriteFile([]byte("a")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := dir.WatchPoll(ctx, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("WatchPoll: %v", err)
+	}
+
+	// A write can be observed half-done by one scan and finished by the next,
+	// so events other than the expected one are skipped.
+	expect := func(t *testing.T, want Event) {
+		t.Helper()
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case e := <-events:
+				if e == want {
+					return
+				}
+			case <-timeout:
+				t.Fatalf("timed out waiting for %v", want)
+			}
+		}
+	}
+
+	created := dir.Join("b.txt")
+	if err := created.WriteFile([]byte("b")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	expect(t, Event{created, EventCreate})
+
+	if err := file.WriteFile([]byte("changed")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	expect(t, Event{file, EventModify})
+
+	if err := created.Delete(); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	expect(t, Event{created, EventDelete})
+
+	cancel()
+	for range events {
+	}
+
+	if _, err := dir.WatchPoll(context.Background(), 0); err == nil {
+		t.Errorf("expected error for a zero interval, got nil")
+	}
+	if _, err := dir.Join("missing").WatchPoll(context.Background(), time.Second); err == nil {
+		t.Errorf("expected error for a missing path, got nil")
+	}
+}
+
+func TestWatchFile(t *testing.T) {
+	tempDir := New(t.TempDir())
+	p := tempDir.Join("config.toml")
+	if err := p.WriteFile([]byte("v=1")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	const debounce = 100 * time.Millisecond
+	signals, err := p.WatchFile(ctx, debounce, WatchInitial(), WatchInterval(5*time.Millisecond))
+	if err != nil {
+		t.Fatalf("WatchFile: %v", err)
+	}
+
+	expectSignal := func(what string) {
+		t.Helper()
+		select {
+		case <-signals:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected a signal for %s", what)
+		}
+	}
+	expectQuiet := func(what string) {
+		t.Helper()
+		select {
+		case <-signals:
+			t.Fatalf("expected no further signal after %s", what)
+		case <-time.After(2 * debounce):
+		}
+	}
+
+	expectSignal("the initial load")
+	expectQuiet("the initial load")
+
+	// A burst of writes shorter than the debounce yields one signal.
+	for i := range 5 {
+		if err := p.WriteFile([]byte(strings.Repeat("v=2\n", i+2))); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		time.Sleep(debounce / 5)
+	}
+	expectSignal("a burst of writes")
+	expectQuiet("a burst of writes")
+
+	// An atomic save replaces the file with a new one.
+	tmp := tempDir.Join("config.toml.tmp")
+	if err := tmp.WriteFile([]byte("v=3")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.Rename(tmp.String(), p.String()); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	expectSignal("an atomic save")
+
+	// Writes to the replacement are still seen.
+	if err := p.WriteFile([]byte("v=44")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	expectSignal("a write after the atomic save")
+
+	cancel()
+	select {
+	case _, ok := <-signals:
+		if ok {
+			// A signal may still have been buffered; the close follows.
+			<-signals
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected the channel to be closed")
+	}
+
+	if _, err := tempDir.Join("missing").WatchFile(context.Background(), debounce); err == nil {
+		t.Errorf("expected error for a missing file, got nil")
+	}
+	if _, err := tempDir.WatchFile(context.Background(), debounce); err == nil {
+		t.Errorf("expected error for a directory, got nil")
+	}
+}
@@ -0,0 +1,138 @@
+package ppath
+
+import (
+	"errors"
+	"io/fs"
+	"runtime"
+
+	"github.com/maa3x/errz"
+)
+
+// ErrNoXattr is returned by GetXattr and RemoveXattr when the file has no
+// extended attribute of the given name.
+var ErrNoXattr = errors.New("no such extended attribute")
+
+// GetXattr returns the value of the extended attribute name, such as
+// "user.checksum" on Linux or "com.apple.quarantine" on macOS. Symlinks are
+// followed. A missing attribute fails with an error matching ErrNoXattr, and
+// Windows or a filesystem without extended attributes with one matching
+// errors.ErrUnsupported.
+func (p Path) GetXattr(name string) ([]byte, error) {
+	value, err := getXattr(string(p), name)
+	if err != nil {
+		return nil, xattrError(err, "get extended attribute", p, name)
+	}
+	return value, nil
+}
+
+// SetXattr sets the extended attribute name to value, creating or replacing
+// it. Linux only allows names in a namespace such as "user.".
+func (p Path) SetXattr(name string, value []byte) error {
+	if err := setXattr(string(p), name, value); err != nil {
+		return xattrError(err, "set extended attribute", p, name)
+	}
+	return nil
+}
+
+// ListXattr returns the names of the file's extended attributes, in the
+// order the system reports them.
+func (p Path) ListXattr() ([]string, error) {
+	names, err := listXattr(string(p))
+	if err != nil {
+		return nil, errz.E(err, "list extended attributes").With("path", p)
+	}
+	return names, nil
+}
+
+// RemoveXattr removes the extended attribute name.
+func (p Path) RemoveXattr(name string) error {
+	if err := removeXattr(string(p), name); err != nil {
+		return xattrError(err, "remove extended attribute", p, name)
+	}
+	return nil
+}
+
+// quarantineXattr is the attribute macOS attaches to downloaded files, which
+// makes Gatekeeper check them before they are first opened.
+const quarantineXattr = "com.apple.quarantine"
+
+// IsQuarantined reports whether the file carries the macOS quarantine
+// attribute. It always reports false on other systems.
+func (p Path) IsQuarantined() (bool, error) {
+	if runtime.GOOS != "darwin" {
+		return false, nil
+	}
+	_, err := p.GetXattr(quarantineXattr)
+	if errors.Is(err, ErrNoXattr) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ClearQuarantine removes the macOS quarantine attribute from p and, for a
+// directory, from every entry below it, so downloaded programs run without a
+// Gatekeeper prompt. Symlinks are not followed. Entries without the attribute
+// are fine; other failures are collected and returned together once the rest
+// of the tree is done. On other systems it does nothing.
+func (p Path) ClearQuarantine() error {
+	if runtime.GOOS != "darwin" {
+		return nil
+	}
+	return p.removeXattrTree(quarantineXattr)
+}
+
+func (p Path) removeXattrTree(name string) error {
+	var errs []error
+	err := p.Walk(func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d == nil && path == string(p) {
+				return err
+			}
+			errs = append(errs, errz.E(err, "walk").With("path", path))
+			return nil
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+		if err := Path(path).RemoveXattr(name); err != nil && !errors.Is(err, ErrNoXattr) {
+			errs = append(errs, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return errz.E(err, "walk directory")
+	}
+	return errz.Join(errs...)
+}
+
+func xattrError(err error, msg string, p Path, name string) error {
+	if isNoXattr(err) {
+		return errz.E(ErrNoXattr).With("path", p).With("name", name)
+	}
+	return errz.E(err, msg).With("path", p).With("name", name)
+}
+
+// copyXattrs copies every extended attribute of src to dst. A source on a
+// filesystem without extended attributes has none to copy.
+func copyXattrs(src, dst Path) error {
+	names, err := src.ListXattr()
+	if errors.Is(err, errors.ErrUnsupported) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		value, err := src.GetXattr(name)
+		if errors.Is(err, ErrNoXattr) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := dst.SetXattr(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
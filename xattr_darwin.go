@@ -0,0 +1,13 @@
+//go:build darwin
+
+package ppath
+
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+func isNoXattr(err error) bool {
+	return errors.Is(err, unix.ENOATTR)
+}
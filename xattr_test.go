@@ -0,0 +1,124 @@
+package ppath
+
+import (
+	"errors"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+// xattrName is an attribute name that unprivileged users may set on both
+// Linux, which requires the "user." namespace, and macOS.
+const xattrName = "user.ppath.test"
+
+func TestXattr(t *testing.T) {
+	p := New(t.TempDir()).Join("file.txt")
+	if err := p.WriteFile(testContent); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	err := p.SetXattr(xattrName, []byte("tagged"))
+	if runtime.GOOS == "windows" {
+		if !errors.Is(err, errors.ErrUnsupported) {
+			t.Errorf("expected errors.ErrUnsupported, got %v", err)
+		}
+		return
+	}
+	if errors.Is(err, errors.ErrUnsupported) {
+		t.Skip("filesystem does not support extended attributes")
+	}
+	if err != nil {
+		t.Fatalf("SetXattr: %v", err)
+	}
+
+	if value, err := p.GetXattr(xattrName); err != nil || string(value) != "tagged" {
+		t.Errorf("expected tagged, got %q, error: %v", value, err)
+	}
+	if err := p.SetXattr(xattrName, nil); err != nil {
+		t.Fatalf("SetXattr: %v", err)
+	}
+	if value, err := p.GetXattr(xattrName); err != nil || len(value) != 0 {
+		t.Errorf("expected empty value, got %q, error: %v", value, err)
+	}
+
+	names, err := p.ListXattr()
+	if err != nil {
+		t.Fatalf("ListXattr: %v", err)
+	}
+	if !slices.Contains(names, xattrName) {
+		t.Errorf("expected %v to contain %s", names, xattrName)
+	}
+
+	if err := p.RemoveXattr(xattrName); err != nil {
+		t.Fatalf("RemoveXattr: %v", err)
+	}
+	if _, err := p.GetXattr(xattrName); !errors.Is(err, ErrNoXattr) {
+		t.Errorf("expected ErrNoXattr, got %v", err)
+	}
+	if err := p.RemoveXattr(xattrName); !errors.Is(err, ErrNoXattr) {
+		t.Errorf("expected ErrNoXattr, got %v", err)
+	}
+
+	if _, err := p.Dir().Join("missing").ListXattr(); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
+func TestQuarantine(t *testing.T) {
+	p := New(t.TempDir()).Join("tool")
+	if err := p.WriteFile(testContent); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if runtime.GOOS != "darwin" {
+		if ok, err := p.IsQuarantined(); err != nil || ok {
+			t.Errorf("expected false, got %v, error: %v", ok, err)
+		}
+		if err := p.Dir().Join("missing").ClearQuarantine(); err != nil {
+			t.Errorf("expected no-op, got %v", err)
+		}
+		return
+	}
+
+	if err := p.SetXattr(quarantineXattr, []byte("0081;00000000;Safari;")); err != nil {
+		t.Skipf("SetXattr: %v", err)
+	}
+	if ok, err := p.IsQuarantined(); err != nil || !ok {
+		t.Errorf("expected true, got %v, error: %v", ok, err)
+	}
+	if err := p.Dir().ClearQuarantine(); err != nil {
+		t.Fatalf("ClearQuarantine: %v", err)
+	}
+	if ok, err := p.IsQuarantined(); err != nil || ok {
+		t.Errorf("expected false, got %v, error: %v", ok, err)
+	}
+}
+
+func TestRemoveXattrTree(t *testing.T) {
+	root := New(t.TempDir())
+	files := []Path{root.Join("a"), root.Join("sub", "b")}
+	for _, f := range files {
+		if err := f.WriteFile(nil); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	for _, f := range append(files, root.Join("sub")) {
+		if err := f.SetXattr(xattrName, []byte("x")); err != nil {
+			t.Skipf("SetXattr: %v", err)
+		}
+	}
+	if err := root.Join("plain").WriteFile(nil); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	if err := root.removeXattrTree(xattrName); err != nil {
+		t.Fatalf("removeXattrTree: %v", err)
+	}
+	for _, f := range append(files, root.Join("sub")) {
+		if _, err := f.GetXattr(xattrName); !errors.Is(err, ErrNoXattr) {
+			t.Errorf("%s: expected ErrNoXattr, got %v", f, err)
+		}
+	}
+	if err := root.Join("missing").removeXattrTree(xattrName); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
@@ -0,0 +1,33 @@
+//go:build windows
+
+package ppath
+
+import (
+	"errors"
+
+	"github.com/maa3x/errz"
+)
+
+func errXattrUnsupported() error {
+	return errz.E(errors.ErrUnsupported, "extended attributes are not supported on windows")
+}
+
+func getXattr(string, string) ([]byte, error) {
+	return nil, errXattrUnsupported()
+}
+
+func setXattr(string, string, []byte) error {
+	return errXattrUnsupported()
+}
+
+func listXattr(string) ([]string, error) {
+	return nil, errXattrUnsupported()
+}
+
+func removeXattr(string, string) error {
+	return errXattrUnsupported()
+}
+
+func isNoXattr(error) bool {
+	return false
+}